@@ -1,35 +1,109 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/wamphlett/bezel-project-patcher/pkg/batch"
+	"github.com/wamphlett/bezel-project-patcher/pkg/emulationstation"
 	"github.com/wamphlett/bezel-project-patcher/pkg/files"
 	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+	"github.com/wamphlett/bezel-project-patcher/pkg/review"
 )
 
 var (
-	commit        *bool
-	fuzzyMatching *bool
-	exactOnly     *bool
+	commit           *bool
+	fuzzyMatching    *bool
+	scoredMatching   *bool
+	minimumScore     *float64
+	exactOnly        *bool
+	validateOverlays *bool
+	recursive        *bool
+	inspectArchives  *bool
+	configRoot       *string
+	includeExt       *string
+	excludeExt       *string
+	noDefaultExclude *bool
+	failFast         *bool
+	interactive      *bool
+	regionPriority   *string
+	articles         *string
+	linkMode         *string
+	fallbackTemplate *string
+	fallbackOverlay  *string
+	reportFormat     *string
+	reportPath       *string
+	datPath          *string
+	hashRoms         *bool
+	jobs             *int
+	includeGenerated *bool
 )
 
 func init() {
 	commit = flag.Bool("commit", false, "commit will write the new config files")
-	exactOnly = flag.Bool("exact-only", false, "matching will only include exact matches")
+	exactOnly = flag.Bool("exact-only", false, "matching will only include exact matches, DAT matches from --dat are also skipped")
 	fuzzyMatching = flag.Bool("fuzzy", false, "matching will include fuzzy matches")
+	scoredMatching = flag.Bool("scored", false, "matching will include fuzzy matches and names which are similar enough to score above --min-score")
+	minimumScore = flag.Float64("min-score", patching.DefaultMinimumScore, "the lowest similarity score between 0 and 1 which counts as a scored match")
+	recursive = flag.Bool("recursive", false, "ROMs in subdirectories of the ROM directory will also be patched")
+	inspectArchives = flag.Bool("inspect-archives", false, "the names of the files inside zip archives will also be used for matching")
+	validateOverlays = flag.Bool("validate-overlays", false, "configs with a missing overlay config or image will not be used")
+	includeExt = flag.String("include-ext", "", "comma separated list of the only ROM file extensions to patch i.e. .n64,.z64")
+	excludeExt = flag.String("exclude-ext", "", "comma separated list of ROM file extensions to ignore i.e. .txt,.srm")
+	interactive = flag.Bool("interactive", false, "ask before creating configs from alternate, fuzzy or scored matches and for ROMs which match several configs")
+	failFast = flag.Bool("fail-fast", false, "stop creating config files as soon as one of them cannot be created")
+	noDefaultExclude = flag.Bool("no-default-excludes", false, "files which are known not to be ROMs (saves, media etc) will not be ignored")
+	regionPriority = flag.String("region-priority", strings.Join(patching.DefaultRegionPriority, ","), "comma separated list of regions to prefer when several configs match the same ROM i.e. USA,Europe,Japan")
+	articles = flag.String("articles", strings.Join(defaultArticleLanguages(), ","), "comma separated list of languages whose articles are moved between the start and end of titles i.e. en,de. languages without default articles list their own i.e. nl:de|het")
+	linkMode = flag.String("link-mode", string(patching.LinkModeCopy), "how new config files are produced from their source config: copy, symlink or hardlink")
+	fallbackTemplate = flag.String("fallback-template", "", "a config template used to generate configs for ROMs without a matching config. supports {rom_name}, {system} and {overlay}")
+	fallbackOverlay = flag.String("fallback-overlay", "", "the generic overlay used for {overlay} in the fallback template. supports {system}")
+	reportFormat = flag.String("report-format", string(patching.ReportFormatText), "the format of the report written alongside the log: text, json or csv. text only writes the log")
+	reportPath = flag.String("report-path", "", "the directory json and csv reports are written to, defaults to the config directory")
+	datPath = flag.String("dat", "", "a No-Intro or Redump DAT (Logiqx XML or ClrMamePro) used to resolve ROMs to their canonical names before matching")
+	hashRoms = flag.Bool("hash-roms", false, "ROMs will also be looked up in the DAT by their CRC32 and SHA-1 checksums. this is slow for large collections")
+	jobs = flag.Int("jobs", runtime.NumCPU(), "how many ROMs are scanned, hashed and inspected at the same time")
+	includeGenerated = flag.Bool("include-generated", false, "configs created by previous patch runs will also be used as match sources")
+	configRoot = flag.String("config-root", emulationstation.DefaultConfigRoot, "the directory containing the RetroArch core config directories, used by discover")
 }
 
 func main() {
 	flag.Parse()
-	if len(flag.Args()) != 2 {
+
+	switch flag.Arg(0) {
+	case "rollback":
+		rollback(flag.Args()[1:])
+	case "prune":
+		prune(flag.Args()[1:])
+	case "plan":
+		writePlan(flag.Args()[1:])
+	case "apply":
+		applyPlan(flag.Args()[1:])
+	case "batch":
+		runBatch(flag.Args()[1:])
+	case "discover":
+		discover(flag.Args()[1:])
+	default:
+		patch(flag.Args())
+	}
+}
+
+// patch patches a config directory using the ROMs in a ROM directory
+func patch(args []string) {
+	if len(args) != 2 {
 		fmt.Println("expected 2 arguments. example: bezel-project-patcher <path-to-config-directory> <path-to-rom-directory>")
 		return
 	}
 
-	if *exactOnly && *fuzzyMatching {
-		fmt.Println("cannot use both fuzzy matching (--fuzzy) and exact only matching (--exact-only) together")
+	if *exactOnly && (*fuzzyMatching || *scoredMatching) {
+		fmt.Println("cannot use fuzzy matching (--fuzzy) or scored matching (--scored) with exact only matching (--exact-only)")
 		return
 	}
 
@@ -37,21 +111,39 @@ func main() {
 		fmt.Println("DRY RUN ONLY. No files will be modified.")
 	}
 
-	configDirectory := flag.Arg(0)
-	romDirectory := flag.Arg(1)
+	configDirectory := args[0]
+	romDirectory := args[1]
 
-	matchFlag := patching.MatchTypeAlternate
-	if *exactOnly {
-		matchFlag = patching.MatchTypeExact
-	} else if *fuzzyMatching {
-		matchFlag = patching.MatchTypeFuzzy
+	matchFlag, err := patching.ParseMatchType(matchLevel())
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	opts, err := patcherOptions()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	format, err := patching.ParseReportFormat(*reportFormat)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
 	}
 
 	fileManager := files.FileManager{}
-	patcher := patching.NewPatcher(&fileManager, *commit)
+	patcher := patching.NewPatcher(&fileManager, *commit, opts...)
 
-	if err := patcher.PatchDirectory(configDirectory, romDirectory, matchFlag); err != nil {
-		fmt.Errorf("failed to successfully patch directory: %s", err.Error())
+	result, err := patcher.PatchDirectory(configDirectory, romDirectory, matchFlag)
+	if result != nil {
+		writeReport(result, format)
+	}
+	if err != nil {
+		fmt.Printf("failed to successfully patch directory: %s\n", err.Error())
+		if result != nil {
+			printFailedFiles(result)
+		}
 		os.Exit(1)
 	}
 
@@ -63,3 +155,436 @@ func main() {
 
 	fmt.Printf("Successfully patched config directory %s. See the log file for more information.", configDirectory)
 }
+
+// matchLevel returns the name of the match level selected by the command line flags
+func matchLevel() string {
+	if *exactOnly {
+		return string(patching.MatchTypeExact)
+	} else if *scoredMatching {
+		return string(patching.MatchTypeScored)
+	} else if *fuzzyMatching {
+		return string(patching.MatchTypeFuzzy)
+	}
+	return string(patching.MatchTypeAlternate)
+}
+
+// patcherOptions returns the patcher options selected by the command line flags
+func patcherOptions() ([]patching.Option, error) {
+	mode, err := patching.ParseLinkMode(*linkMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if *jobs < 1 {
+		return nil, errors.New("--jobs must be at least 1")
+	}
+	if *minimumScore < 0 || *minimumScore > 1 {
+		return nil, errors.New("--min-score must be between 0 and 1")
+	}
+
+	opts := []patching.Option{patching.WithLinkMode(mode), patching.WithJobs(*jobs)}
+	if *validateOverlays {
+		opts = append(opts, patching.WithOverlayValidation())
+	}
+	if *recursive {
+		opts = append(opts, patching.WithRecursiveScan())
+	}
+	if *inspectArchives {
+		opts = append(opts, patching.WithArchiveInspection())
+	}
+	if *includeExt != "" {
+		opts = append(opts, patching.WithIncludedExtensions(strings.Split(*includeExt, ",")...))
+	}
+	if *excludeExt != "" {
+		opts = append(opts, patching.WithExcludedExtensions(strings.Split(*excludeExt, ",")...))
+	}
+	if *noDefaultExclude {
+		opts = append(opts, patching.WithoutDefaultExclusions())
+	}
+	if *failFast {
+		opts = append(opts, patching.WithFailFast())
+	}
+	if *includeGenerated {
+		opts = append(opts, patching.WithGeneratedSources())
+	}
+	if *interactive {
+		opts = append(opts, patching.WithReviewer(review.NewTerminalReviewer(os.Stdin, os.Stdout)))
+	}
+	if *minimumScore != patching.DefaultMinimumScore {
+		opts = append(opts, patching.WithMinimumScore(*minimumScore))
+	}
+	if *regionPriority != "" {
+		opts = append(opts, patching.WithRegionPriority(strings.Split(*regionPriority, ",")...))
+	}
+	if *articles != strings.Join(defaultArticleLanguages(), ",") {
+		languageArticles, err := parseArticles(*articles)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, patching.WithArticles(languageArticles))
+	}
+	if *datPath != "" {
+		dat, err := patching.LoadDat(*datPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, patching.WithDat(dat))
+	}
+	if *hashRoms {
+		opts = append(opts, patching.WithRomHashing())
+	}
+	if *fallbackTemplate != "" {
+		contents, err := os.ReadFile(*fallbackTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fallback template: %w", err)
+		}
+		opts = append(opts, patching.WithFallbackTemplate(patching.FallbackTemplate{
+			Name:     filepath.Base(*fallbackTemplate),
+			Contents: string(contents),
+			Overlay:  *fallbackOverlay,
+		}))
+	}
+	return opts, nil
+}
+
+// defaultArticleLanguages returns the languages which have default articles
+func defaultArticleLanguages() []string {
+	languages := []string{}
+	for language := range patching.DefaultArticles() {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// parseArticles returns the articles for a comma separated list of languages. Languages with
+// default articles are given by their code and any others list their articles i.e. "en,nl:de|het".
+func parseArticles(value string) (map[string][]string, error) {
+	defaults := patching.DefaultArticles()
+	languageArticles := map[string][]string{}
+	for _, language := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(language), ":", 2)
+		code := strings.ToLower(parts[0])
+		if code == "" {
+			continue
+		}
+		if len(parts) == 2 {
+			languageArticles[code] = strings.Split(parts[1], "|")
+			continue
+		}
+		articles, ok := defaults[code]
+		if !ok {
+			return nil, fmt.Errorf("no default articles for language %s, list them i.e. %s:de|het", code, code)
+		}
+		languageArticles[code] = articles
+	}
+	return languageArticles, nil
+}
+
+// printFailedFiles lists every config which could not be created
+func printFailedFiles(result *patching.PatchResult) {
+	for _, entry := range result.Failed {
+		fmt.Printf("  %s: %s\n", entry.NewFile, entry.Reason)
+	}
+}
+
+// writeReport writes the report for a patch run in the given format. Text reports are skipped as
+// the log has already been written.
+func writeReport(result *patching.PatchResult, format patching.ReportFormat) {
+	if format == patching.ReportFormatText {
+		return
+	}
+
+	directory := *reportPath
+	if directory == "" {
+		directory = result.ConfigDirectory
+	}
+	path := filepath.Join(directory, patching.ReportFileName(result.RunID, format))
+
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("failed to write report: %s\n", err.Error())
+		return
+	}
+	defer file.Close()
+
+	if err := result.Report.Write(file, format); err != nil {
+		fmt.Printf("failed to write report: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("wrote report to: %s\n", path)
+}
+
+// runBatch patches every system listed in a systems manifest
+func runBatch(args []string) {
+	if len(args) != 1 {
+		fmt.Println("expected 1 argument. example: bezel-project-patcher batch <path-to-manifest.yaml|json>")
+		return
+	}
+
+	manifest, err := batch.LoadManifest(args[0])
+	if err != nil {
+		fmt.Printf("failed to load manifest: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	runSystems(manifest.Systems, filepath.Dir(args[0]), fmt.Sprintf("batch %s", args[0]))
+}
+
+// discover patches every EmulationStation system which has Bezel Project configs installed
+func discover(args []string) {
+	if len(args) > 1 {
+		fmt.Println("expected at most 1 argument. example: bezel-project-patcher discover [path-to-es_systems.cfg]")
+		return
+	}
+
+	if *exactOnly && (*fuzzyMatching || *scoredMatching) {
+		fmt.Println("cannot use fuzzy matching (--fuzzy) or scored matching (--scored) with exact only matching (--exact-only)")
+		return
+	}
+
+	systemsPath := ""
+	if len(args) == 1 {
+		systemsPath = args[0]
+	} else {
+		// EmulationStation prefers the user's own system definitions over the global ones
+		homeDir, _ := os.UserHomeDir()
+		for _, path := range []string{filepath.Join(homeDir, ".emulationstation", "es_systems.cfg"), "/etc/emulationstation/es_systems.cfg"} {
+			if _, err := os.Stat(path); err == nil {
+				systemsPath = path
+				break
+			}
+		}
+		if systemsPath == "" {
+			fmt.Println("unable to find es_systems.cfg. example: bezel-project-patcher discover <path-to-es_systems.cfg>")
+			os.Exit(1)
+		}
+	}
+
+	esSystems, err := emulationstation.LoadSystems(systemsPath)
+	if err != nil {
+		fmt.Printf("failed to load systems: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	fileManager := files.FileManager{}
+	systems := batch.Discover(&fileManager, esSystems, *configRoot, matchLevel())
+	if len(systems) == 0 {
+		fmt.Printf("no systems with bezel configs found in %s\n", *configRoot)
+		return
+	}
+
+	runSystems(systems, *configRoot, fmt.Sprintf("discover %s", systemsPath))
+}
+
+// runSystems patches each of the given systems and writes a consolidated summary to the summary directory
+func runSystems(systems []batch.System, summaryDir, command string) {
+	if !*commit {
+		fmt.Println("DRY RUN ONLY. No files will be modified.")
+	}
+
+	opts, err := patcherOptions()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	format, err := patching.ParseReportFormat(*reportFormat)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fileManager := files.FileManager{}
+	patcher := patching.NewPatcher(&fileManager, *commit, opts...)
+
+	results := batch.Run(patcher, systems)
+	for _, result := range results {
+		if result.PatchResult != nil {
+			writeReport(result.PatchResult, format)
+		}
+	}
+	summary := batch.Summary(results, *commit)
+	fmt.Print(summary)
+
+	summaryPath := filepath.Join(summaryDir, fmt.Sprintf("batch-summary.%d.log", time.Now().Unix()))
+	if err := os.WriteFile(summaryPath, []byte(summary), 0644); err != nil {
+		fmt.Printf("failed to write summary file: %s\n", err.Error())
+	} else {
+		fmt.Printf("wrote summary file to: %s\n", summaryPath)
+	}
+
+	if batch.Failed(results) {
+		os.Exit(1)
+	}
+
+	if !*commit {
+		fmt.Println("Batch finished but no files were modified. It is strongly recommended to check logs before committing the changes.")
+		fmt.Printf("Run 'bezel-project-patcher --commit %s' to commit the changes\n", command)
+	}
+}
+
+// rollback removes the files created by a previous committed patch run
+func rollback(args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("expected 1 or 2 arguments. example: bezel-project-patcher rollback <path-to-config-directory> [run-id]")
+		return
+	}
+
+	if !*commit {
+		fmt.Println("DRY RUN ONLY. No files will be modified.")
+	}
+
+	configDirectory := args[0]
+	runID := ""
+	if len(args) == 2 {
+		runID = args[1]
+	}
+
+	fileManager := files.FileManager{}
+	patcher := patching.NewPatcher(&fileManager, *commit)
+
+	if err := patcher.Rollback(configDirectory, runID); err != nil {
+		fmt.Printf("failed to roll back patch: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if !*commit {
+		fmt.Println("Rollback finished but no files were removed. It is strongly recommended to check logs before committing the changes.")
+		// only suggest the run ID if one was given so the command can be copied as is
+		command := []string{"rollback", configDirectory}
+		if runID != "" {
+			command = append(command, runID)
+		}
+		fmt.Printf("Run 'bezel-project-patcher --commit %s' to commit the changes\n", strings.Join(command, " "))
+		return
+	}
+
+	fmt.Printf("Successfully rolled back config directory %s. See the log file for more information.", configDirectory)
+}
+
+// prune moves configs created by previous patch runs whose ROM no longer exists into quarantine
+func prune(args []string) {
+	if len(args) != 1 {
+		fmt.Println("expected 1 argument. example: bezel-project-patcher prune <path-to-config-directory>")
+		return
+	}
+
+	if !*commit {
+		fmt.Println("DRY RUN ONLY. No files will be modified.")
+	}
+
+	configDirectory := args[0]
+
+	fileManager := files.FileManager{}
+	patcher := patching.NewPatcher(&fileManager, *commit)
+
+	if err := patcher.Prune(configDirectory); err != nil {
+		fmt.Printf("failed to prune config directory: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if !*commit {
+		fmt.Println("Prune finished but no files were moved. It is strongly recommended to check logs before committing the changes.")
+		fmt.Printf("Run 'bezel-project-patcher --commit prune %s' to commit the changes\n", configDirectory)
+		return
+	}
+
+	fmt.Printf("Successfully pruned config directory %s. See the log file for more information.", configDirectory)
+}
+
+// writePlan works out exactly which files patching would create and writes them to a plan file
+// which can be reviewed before it is applied
+func writePlan(args []string) {
+	if len(args) != 2 {
+		fmt.Println("expected 2 arguments. example: bezel-project-patcher plan <path-to-config-directory> <path-to-rom-directory>")
+		return
+	}
+
+	if *exactOnly && (*fuzzyMatching || *scoredMatching) {
+		fmt.Println("cannot use fuzzy matching (--fuzzy) or scored matching (--scored) with exact only matching (--exact-only)")
+		return
+	}
+
+	// plans may be applied from anywhere so they always record absolute paths
+	configDirectory, err := filepath.Abs(args[0])
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	romDirectory, err := filepath.Abs(args[1])
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	matchFlag, err := patching.ParseMatchType(matchLevel())
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	opts, err := patcherOptions()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	// planning never modifies any files
+	fileManager := files.FileManager{}
+	patcher := patching.NewPatcher(&fileManager, false, opts...)
+
+	result, err := patcher.PatchDirectory(configDirectory, romDirectory, matchFlag)
+	if err != nil {
+		fmt.Printf("failed to plan patch: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	planPath := filepath.Join(configDirectory, patching.PlanFileName(result.RunID))
+	if err := result.Plan.Save(planPath); err != nil {
+		fmt.Printf("failed to write plan: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("wrote plan with %d new files to: %s\n", len(result.Plan.Operations), planPath)
+	fmt.Printf("Run 'bezel-project-patcher --commit apply %s' to apply the plan\n", planPath)
+}
+
+// applyPlan creates exactly the files listed in a plan file
+func applyPlan(args []string) {
+	if len(args) != 1 {
+		fmt.Println("expected 1 argument. example: bezel-project-patcher apply <path-to-plan>")
+		return
+	}
+
+	if !*commit {
+		fmt.Println("DRY RUN ONLY. No files will be modified.")
+	}
+
+	plan, err := patching.LoadPlan(args[0])
+	if err != nil {
+		fmt.Printf("failed to load plan: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	opts := []patching.Option{}
+	if *failFast {
+		opts = append(opts, patching.WithFailFast())
+	}
+
+	fileManager := files.FileManager{}
+	patcher := patching.NewPatcher(&fileManager, *commit, opts...)
+
+	if err := patcher.Apply(plan); err != nil {
+		fmt.Printf("failed to apply plan: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if !*commit {
+		fmt.Println("Plan can still be applied but no files were modified.")
+		fmt.Printf("Run 'bezel-project-patcher --commit apply %s' to commit the changes\n", args[0])
+		return
+	}
+
+	fmt.Printf("Successfully applied plan to config directory %s. See the log file for more information.", plan.ConfigDirectory)
+}
@@ -0,0 +1,61 @@
+package batch
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/emulationstation"
+)
+
+// DirectoryLister lists the contents of a directory
+type DirectoryLister interface {
+	GetDirectoryContents(directoryPath string) ([]string, error)
+}
+
+// Discover builds a system to patch for every EmulationStation system which has both a ROM
+// directory and at least one RetroArch core config directory containing game configs. Systems
+// which can run on several cores produce a system for every core with configs.
+func Discover(lister DirectoryLister, esSystems []emulationstation.System, configRoot, match string) []System {
+	systems := []System{}
+	for _, esSystem := range esSystems {
+		if _, err := lister.GetDirectoryContents(esSystem.Path); err != nil {
+			continue
+		}
+
+		configDirectories := []string{}
+		for _, configDirectory := range emulationstation.ConfigDirectories(configRoot, esSystem) {
+			if hasConfigFiles(lister, configDirectory) {
+				configDirectories = append(configDirectories, configDirectory)
+			}
+		}
+
+		for _, configDirectory := range configDirectories {
+			name := esSystem.Name
+			if len(configDirectories) > 1 {
+				name = fmt.Sprintf("%s (%s)", esSystem.Name, filepath.Base(configDirectory))
+			}
+			systems = append(systems, System{
+				Name:            name,
+				ConfigDirectory: configDirectory,
+				RomDirectory:    esSystem.Path,
+				Match:           match,
+				Extensions:      esSystem.Extensions,
+			})
+		}
+	}
+	return systems
+}
+
+// hasConfigFiles returns true if the directory exists and contains at least one config file
+func hasConfigFiles(lister DirectoryLister, directoryPath string) bool {
+	contents, err := lister.GetDirectoryContents(directoryPath)
+	if err != nil {
+		return false
+	}
+	for _, file := range contents {
+		if filepath.Ext(file) == ".cfg" {
+			return true
+		}
+	}
+	return false
+}
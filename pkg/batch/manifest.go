@@ -0,0 +1,99 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+// System describes a single config directory and ROM directory pair to patch
+type System struct {
+	Name            string `json:"name" yaml:"name"`
+	ConfigDirectory string `json:"config_dir" yaml:"config_dir"`
+	RomDirectory    string `json:"rom_dir" yaml:"rom_dir"`
+	// Match is the match level used for the system (exact, alternate, fuzzy, scored or the name of a
+	// custom matcher). It is checked against the patcher's matchers when the system is patched. When
+	// empty the alternate match level is used.
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Extensions restricts the ROMs to files with one of the given extensions. An explicit list
+	// replaces the default exclusions of files which are never ROMs (saves, scraped media etc), so
+	// any listed extension is used even if it would normally be excluded. When empty every file in
+	// the ROM directory is used apart from the default exclusions.
+	Extensions []string `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+	// Dat is the path to a DAT used to resolve the system's ROMs to their canonical names. Relative
+	// paths are relative to the manifest.
+	Dat string `json:"dat,omitempty" yaml:"dat,omitempty"`
+}
+
+// Manifest lists every system which should be patched in a batch
+type Manifest struct {
+	Systems []System `json:"systems" yaml:"systems"`
+}
+
+// LoadManifest reads a systems manifest from a YAML or JSON file. The format is chosen by
+// the file extension.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, manifest)
+	case ".json":
+		err = json.Unmarshal(data, manifest)
+	default:
+		return nil, fmt.Errorf("unsupported manifest format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := manifest.validate(); err != nil {
+		return nil, err
+	}
+	for i, system := range manifest.Systems {
+		if system.Dat != "" && !filepath.IsAbs(system.Dat) {
+			manifest.Systems[i].Dat = filepath.Join(filepath.Dir(path), system.Dat)
+		}
+	}
+	return manifest, nil
+}
+
+// matchLevel returns the name of the match level which should be used when patching the system
+func (s System) matchLevel() string {
+	if s.Match == "" {
+		return string(patching.MatchTypeAlternate)
+	}
+	return s.Match
+}
+
+// validate makes sure every system in the manifest can be patched
+func (m *Manifest) validate() error {
+	if len(m.Systems) == 0 {
+		return errors.New("manifest does not contain any systems")
+	}
+	names := map[string]bool{}
+	for i, system := range m.Systems {
+		if system.Name == "" {
+			return fmt.Errorf("system %d is missing a name", i+1)
+		}
+		if names[system.Name] {
+			return fmt.Errorf("system %s is listed more than once", system.Name)
+		}
+		names[system.Name] = true
+		if system.ConfigDirectory == "" || system.RomDirectory == "" {
+			return fmt.Errorf("system %s must have both a config_dir and a rom_dir", system.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadManifest(t *testing.T) {
+	expectedSystems := []System{
+		{
+			Name:            "n64",
+			ConfigDirectory: "/opt/retropie/configs/all/retroarch/config/Mupen64Plus GLES2",
+			RomDirectory:    "/home/pi/RetroPie/roms/n64",
+			Match:           "exact",
+		},
+		{
+			Name:            "snes",
+			ConfigDirectory: "/opt/retropie/configs/all/retroarch/config/Snes9x",
+			RomDirectory:    "/home/pi/RetroPie/roms/snes",
+		},
+	}
+
+	tt := map[string]struct {
+		fileName string
+		contents string
+	}{
+		"yaml manifest": {
+			fileName: "systems.yaml",
+			contents: `systems:
+  - name: n64
+    config_dir: /opt/retropie/configs/all/retroarch/config/Mupen64Plus GLES2
+    rom_dir: /home/pi/RetroPie/roms/n64
+    match: exact
+  - name: snes
+    config_dir: /opt/retropie/configs/all/retroarch/config/Snes9x
+    rom_dir: /home/pi/RetroPie/roms/snes
+`,
+		},
+		"json manifest": {
+			fileName: "systems.json",
+			contents: `{"systems": [
+  {"name": "n64", "config_dir": "/opt/retropie/configs/all/retroarch/config/Mupen64Plus GLES2", "rom_dir": "/home/pi/RetroPie/roms/n64", "match": "exact"},
+  {"name": "snes", "config_dir": "/opt/retropie/configs/all/retroarch/config/Snes9x", "rom_dir": "/home/pi/RetroPie/roms/snes"}
+]}`,
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.fileName)
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0644))
+
+			manifest, err := LoadManifest(path)
+			require.NoError(t, err)
+			assert.Equal(t, expectedSystems, manifest.Systems)
+		})
+	}
+}
+
+func TestLoadInvalidManifest(t *testing.T) {
+	tt := map[string]struct {
+		fileName string
+		contents string
+	}{
+		"unsupported format": {
+			fileName: "systems.txt",
+			contents: "n64",
+		},
+		"no systems": {
+			fileName: "systems.yaml",
+			contents: "systems: []\n",
+		},
+		"missing rom directory": {
+			fileName: "systems.yaml",
+			contents: "systems:\n  - name: n64\n    config_dir: /configs/n64\n",
+		},
+		"duplicate system": {
+			fileName: "systems.yaml",
+			contents: "systems:\n  - name: n64\n    config_dir: /configs/n64\n    rom_dir: /roms/n64\n  - name: n64\n    config_dir: /configs/n64\n    rom_dir: /roms/n64\n",
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.fileName)
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0644))
+
+			_, err := LoadManifest(path)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+// Result records the outcome of patching a single system
+type Result struct {
+	System      System
+	PatchResult *patching.PatchResult
+	Err         error
+}
+
+// errNotPatched is recorded against the systems which were not patched because fail fast stopped
+// the batch at an earlier failure
+var errNotPatched = errors.New("not patched after an earlier failure")
+
+// Run patches every system in turn using the given patcher. A failure to patch one system does
+// not stop the remaining systems from being patched unless the patcher fails fast.
+func Run(patcher *patching.Patcher, systems []System) []Result {
+	results := make([]Result, len(systems))
+	stopped := false
+	for i, system := range systems {
+		results[i] = Result{System: system}
+		if stopped {
+			results[i].Err = errNotPatched
+			continue
+		}
+
+		matchFlag, err := patcher.ParseMatchType(system.matchLevel())
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		systemPatcher := patcher
+		if len(system.Extensions) > 0 {
+			systemPatcher = systemPatcher.With(patching.WithIncludedExtensions(system.Extensions...))
+		}
+		if system.Dat != "" {
+			dat, err := patching.LoadDat(system.Dat)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			systemPatcher = systemPatcher.With(patching.WithDat(dat))
+		}
+
+		fmt.Printf("patching %s\n", system.Name)
+		results[i].PatchResult, results[i].Err = systemPatcher.PatchDirectory(system.ConfigDirectory, system.RomDirectory, matchFlag)
+		stopped = results[i].Err != nil && patcher.FailsFast()
+	}
+	return results
+}
+
+// Failed returns true if any of the systems failed to patch
+func Failed(results []Result) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary produces a consolidated summary of every system in the batch
+func Summary(results []Result, commit bool) string {
+	summary := ""
+	if !commit {
+		summary = "[DRY]\n\n"
+	}
+
+	total := patching.PatchResult{}
+	failed := []string{}
+	lines := []string{}
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.System.Name, result.Err.Error()))
+		}
+		// systems which failed to create some files still have a result
+		if result.PatchResult == nil {
+			continue
+		}
+		r := result.PatchResult
+		total.ConfigCount += r.ConfigCount
+		total.GeneratedConfigs += r.GeneratedConfigs
+		total.RomCount += r.RomCount
+		total.MissingRoms += r.MissingRoms
+		total.MissingConfigs += r.MissingConfigs
+		total.BrokenConfigs += r.BrokenConfigs
+		total.IgnoredFiles += r.IgnoredFiles
+		total.CreatedFiles += r.CreatedFiles
+		total.SkippedFiles += r.SkippedFiles
+		total.FailedFiles += r.FailedFiles
+
+		line := fmt.Sprintf("%s (%s): %d roms, %d configs, %d created, %d skipped, %d failed, %d missing config, %d missing ROMs",
+			result.System.Name, result.System.matchLevel(), r.RomCount, r.ConfigCount, r.CreatedFiles, r.SkippedFiles, r.FailedFiles, r.MissingConfigs, r.MissingRoms)
+		if r.LogPath != "" {
+			line += fmt.Sprintf("\n    log: %s", r.LogPath)
+		}
+		lines = append(lines, line)
+	}
+
+	summary += fmt.Sprintf("Patched %d of %d systems\n\n", len(results)-len(failed), len(results))
+	summary += fmt.Sprintf("Found %d config files (%d generated)\nFound %d roms\nIgnored %d files\n\n", total.ConfigCount, total.GeneratedConfigs, total.RomCount, total.IgnoredFiles)
+	summary += fmt.Sprintf("Missing ROMs: %d\nMissing config: %d\n", total.MissingRoms, total.MissingConfigs)
+	if total.BrokenConfigs > 0 {
+		summary += fmt.Sprintf("Broken config: %d\n", total.BrokenConfigs)
+	}
+	summary += "\n"
+	summary += fmt.Sprintf("Created %d new files\n", total.CreatedFiles)
+	summary += fmt.Sprintf("Skipped %d new files\n", total.SkippedFiles)
+	summary += fmt.Sprintf("Failed %d new files\n\n", total.FailedFiles)
+
+	if len(failed) > 0 {
+		summary += fmt.Sprintf("FAILED SYSTEMS\n%s\n\n", strings.Join(failed, "\n"))
+	}
+
+	if len(lines) > 0 {
+		summary += fmt.Sprintf("SYSTEMS\n%s\n\n", strings.Join(lines, "\n"))
+	}
+
+	return summary
+}
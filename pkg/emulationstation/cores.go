@@ -0,0 +1,59 @@
+package emulationstation
+
+import "path/filepath"
+
+// DefaultConfigRoot is the directory RetroPie keeps the RetroArch per-core config directories in
+const DefaultConfigRoot = "/opt/retropie/configs/all/retroarch/config"
+
+// CoreConfigDirectories maps EmulationStation system names to the names of the RetroArch core
+// config directories the Bezel Project installs game configs into. Systems can run on more than
+// one core so every likely core is listed, most common first.
+var CoreConfigDirectories = map[string][]string{
+	"arcade":          {"MAME 2003-Plus", "MAME 2003 (0.78)", "MAME 2010", "FinalBurn Neo", "FB Alpha"},
+	"atari2600":       {"Stella", "Stella 2014"},
+	"atari5200":       {"Atari800"},
+	"atari7800":       {"ProSystem"},
+	"atarilynx":       {"Beetle Lynx", "Handy"},
+	"coleco":          {"blueMSX"},
+	"colecovision":    {"blueMSX"},
+	"dreamcast":       {"Flycast"},
+	"fba":             {"FinalBurn Neo", "FB Alpha"},
+	"fbneo":           {"FinalBurn Neo"},
+	"gamegear":        {"Genesis Plus GX", "PicoDrive"},
+	"gb":              {"Gambatte", "SameBoy", "mGBA"},
+	"gba":             {"mGBA", "gpSP", "VBA-M"},
+	"gbc":             {"Gambatte", "SameBoy", "mGBA"},
+	"genesis":         {"Genesis Plus GX", "PicoDrive"},
+	"mame-libretro":   {"MAME 2003-Plus", "MAME 2003 (0.78)", "MAME 2010"},
+	"mame2003":        {"MAME 2003 (0.78)"},
+	"mame2003-plus":   {"MAME 2003-Plus"},
+	"mame2010":        {"MAME 2010"},
+	"mastersystem":    {"Genesis Plus GX", "PicoDrive"},
+	"megadrive":       {"Genesis Plus GX", "PicoDrive"},
+	"n64":             {"Mupen64Plus GLES2", "Mupen64Plus-Next", "ParaLLEl N64"},
+	"neogeo":          {"FinalBurn Neo", "FB Alpha", "MAME 2003-Plus"},
+	"nes":             {"FCEUmm", "Nestopia", "QuickNES"},
+	"ngp":             {"Beetle NeoPop"},
+	"ngpc":            {"Beetle NeoPop"},
+	"pcengine":        {"Beetle PCE Fast", "Beetle PCE"},
+	"psx":             {"PCSX-ReARMed", "Beetle PSX HW", "Beetle PSX", "SwanStation"},
+	"saturn":          {"Beetle Saturn", "YabaSanshiro"},
+	"sega32x":         {"PicoDrive"},
+	"segacd":          {"Genesis Plus GX", "PicoDrive"},
+	"sg-1000":         {"Genesis Plus GX"},
+	"snes":            {"Snes9x", "Snes9x 2010", "Snes9x 2005", "Snes9x 2002"},
+	"vectrex":         {"vecx"},
+	"virtualboy":      {"Beetle VB"},
+	"wonderswan":      {"Beetle WonderSwan"},
+	"wonderswancolor": {"Beetle WonderSwan"},
+}
+
+// ConfigDirectories returns the RetroArch config directories which may hold the game configs for
+// the given system. The directories are not checked for existence.
+func ConfigDirectories(configRoot string, system System) []string {
+	directories := []string{}
+	for _, core := range CoreConfigDirectories[system.Name] {
+		directories = append(directories, filepath.Join(configRoot, core))
+	}
+	return directories
+}
@@ -0,0 +1,84 @@
+package emulationstation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// System is a single system definition from an EmulationStation es_systems.cfg file
+type System struct {
+	Name       string
+	FullName   string
+	Path       string
+	Extensions []string
+	Platform   string
+}
+
+// systemList mirrors the XML structure of es_systems.cfg
+type systemList struct {
+	Systems []struct {
+		Name      string `xml:"name"`
+		FullName  string `xml:"fullname"`
+		Path      string `xml:"path"`
+		Extension string `xml:"extension"`
+		Platform  string `xml:"platform"`
+	} `xml:"system"`
+}
+
+// LoadSystems reads every system from an es_systems.cfg file. ROM paths starting with "~" are
+// expanded to the current user's home directory.
+func LoadSystems(path string) ([]System, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	list := systemList{}
+	if err := xml.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	systems := make([]System, len(list.Systems))
+	for i, s := range list.Systems {
+		systems[i] = System{
+			Name:       strings.TrimSpace(s.Name),
+			FullName:   strings.TrimSpace(s.FullName),
+			Path:       expandHome(strings.TrimSpace(s.Path), homeDir),
+			Extensions: parseExtensions(s.Extension),
+			Platform:   strings.TrimSpace(s.Platform),
+		}
+	}
+	return systems, nil
+}
+
+// parseExtensions splits the space separated extension list from a system definition. The list
+// often repeats each extension in upper and lower case so only the unique lower case extensions
+// are returned.
+func parseExtensions(extensions string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, extension := range strings.Fields(extensions) {
+		extension = strings.ToLower(extension)
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		if seen[extension] {
+			continue
+		}
+		seen[extension] = true
+		unique = append(unique, extension)
+	}
+	return unique
+}
+
+// expandHome replaces a leading "~" in the path with the given home directory
+func expandHome(path, homeDir string) string {
+	if homeDir == "" || (path != "~" && !strings.HasPrefix(path, "~/")) {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
@@ -0,0 +1,57 @@
+package emulationstation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSystems(t *testing.T) {
+	contents := `<?xml version="1.0"?>
+<systemList>
+  <system>
+    <name>n64</name>
+    <fullname>Nintendo 64</fullname>
+    <path>/home/pi/RetroPie/roms/n64</path>
+    <extension>.n64 .N64 .v64 .V64 .z64 .Z64 .zip .ZIP</extension>
+    <command>/opt/retropie/supplementary/runcommand/runcommand.sh 0 _SYS_ n64 %ROM%</command>
+    <platform>n64</platform>
+    <theme>n64</theme>
+  </system>
+  <system>
+    <name>snes</name>
+    <fullname>Super Nintendo</fullname>
+    <path>/home/pi/RetroPie/roms/snes</path>
+    <extension>.smc .sfc</extension>
+  </system>
+</systemList>`
+	path := filepath.Join(t.TempDir(), "es_systems.cfg")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+
+	systems, err := LoadSystems(path)
+	require.NoError(t, err)
+	assert.Equal(t, []System{
+		{
+			Name:       "n64",
+			FullName:   "Nintendo 64",
+			Path:       "/home/pi/RetroPie/roms/n64",
+			Extensions: []string{".n64", ".v64", ".z64", ".zip"},
+			Platform:   "n64",
+		},
+		{
+			Name:       "snes",
+			FullName:   "Super Nintendo",
+			Path:       "/home/pi/RetroPie/roms/snes",
+			Extensions: []string{".smc", ".sfc"},
+		},
+	}, systems)
+}
+
+func TestExpandHome(t *testing.T) {
+	assert.Equal(t, "/home/pi/RetroPie/roms/n64", expandHome("~/RetroPie/roms/n64", "/home/pi"))
+	assert.Equal(t, "/roms/n64", expandHome("/roms/n64", "/home/pi"))
+	assert.Equal(t, "~other/roms", expandHome("~other/roms", "/home/pi"))
+}
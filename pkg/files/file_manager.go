@@ -1,7 +1,13 @@
 package files
 
 import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -22,7 +28,8 @@ func (m *FileManager) GetDirectoryContents(directoryPath string) ([]string, erro
 	return fileNames, nil
 }
 
-// CopyFileWithName copies a file with a new name in the given directory
+// CopyFileWithName copies a file with a new name in the given directory. A partially written
+// file is removed if the copy fails.
 func (m *FileManager) CopyFileWithName(directoryPath, srcFileName, newFileName string) error {
 	src := filepath.Join(directoryPath, srcFileName)
 	dst := filepath.Join(directoryPath, newFileName)
@@ -37,14 +44,163 @@ func (m *FileManager) CopyFileWithName(directoryPath, srcFileName, newFileName s
 	if err != nil {
 		return err
 	}
-	defer destination.Close()
 	_, err = io.Copy(destination, source)
+	// errors such as a full disk may only be reported when the file is closed
+	if closeErr := destination.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
 
 	return err
 }
 
+// LinkFileWithName links a file to a new name in the given directory. Symbolic links point at the
+// source file's name so they keep working if the directory is moved.
+func (m *FileManager) LinkFileWithName(directoryPath, srcFileName, newFileName string, symbolic bool) error {
+	dst := filepath.Join(directoryPath, newFileName)
+	if symbolic {
+		return os.Symlink(srcFileName, dst)
+	}
+	return os.Link(filepath.Join(directoryPath, srcFileName), dst)
+}
+
+// WriteFile writes the given contents to a file in the given directory
+func (m *FileManager) WriteFile(directoryPath, fileName string, contents []byte) error {
+	return os.WriteFile(filepath.Join(directoryPath, fileName), contents, 0644)
+}
+
 // FileExists checks if a file exists in the given directory
 func (m *FileManager) FileExists(directoryPath, fileName string) bool {
 	_, err := os.Stat(filepath.Join(directoryPath, fileName))
 	return err == nil
 }
+
+// LinkExists checks if a file exists in the given directory without following symbolic links, so
+// a link to a file which no longer exists still exists
+func (m *FileManager) LinkExists(directoryPath, fileName string) bool {
+	_, err := os.Lstat(filepath.Join(directoryPath, fileName))
+	return err == nil
+}
+
+// IsDirectory checks if the given name in the directory is itself a directory
+func (m *FileManager) IsDirectory(directoryPath, fileName string) bool {
+	info, err := os.Stat(filepath.Join(directoryPath, fileName))
+	return err == nil && info.IsDir()
+}
+
+// ReadFile returns the contents of a file in the given directory
+func (m *FileManager) ReadFile(directoryPath, fileName string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(directoryPath, fileName))
+}
+
+// RemoveFile deletes a file from the given directory
+func (m *FileManager) RemoveFile(directoryPath, fileName string) error {
+	return os.Remove(filepath.Join(directoryPath, fileName))
+}
+
+// MoveFile moves a file from the given directory into a new directory, creating the new directory
+// if it does not exist. Relative symbolic links are recreated so they still point at the same file.
+func (m *FileManager) MoveFile(directoryPath, fileName, newDirectoryPath string) error {
+	if err := os.MkdirAll(newDirectoryPath, 0755); err != nil {
+		return err
+	}
+	path, newPath := filepath.Join(directoryPath, fileName), filepath.Join(newDirectoryPath, fileName)
+
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(target) {
+			if target, err = filepath.Rel(newDirectoryPath, filepath.Join(directoryPath, target)); err != nil {
+				return err
+			}
+			if err := os.Symlink(target, newPath); err != nil {
+				return err
+			}
+			return os.Remove(path)
+		}
+	}
+	return os.Rename(path, newPath)
+}
+
+// HashFile returns the lowercase hex encoded CRC32 and SHA-1 checksums of a file in the given
+// directory. Both are worked out in a single read of the file.
+func (m *FileManager) HashFile(directoryPath, fileName string) (string, string, error) {
+	file, err := os.Open(filepath.Join(directoryPath, fileName))
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	crcHash := crc32.NewIEEE()
+	sha1Hash := sha1.New()
+	if _, err := io.Copy(io.MultiWriter(crcHash, sha1Hash), file); err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("%08x", crcHash.Sum32()), hex.EncodeToString(sha1Hash.Sum(nil)), nil
+}
+
+// GetDirectoryContentsRecursive returns the paths of all the files in the given directory and
+// its subdirectories, relative to the given directory. Directories themselves are not included.
+func (m *FileManager) GetDirectoryContentsRecursive(directoryPath string) ([]string, error) {
+	filePaths := []string{}
+	err := filepath.WalkDir(directoryPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(directoryPath, path)
+		if err != nil {
+			return err
+		}
+		filePaths = append(filePaths, relativePath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filePaths, nil
+}
+
+// GetArchiveContents returns the paths of all the files inside a zip archive in the given directory
+func (m *FileManager) GetArchiveContents(directoryPath, fileName string) ([]string, error) {
+	archive, err := zip.OpenReader(filepath.Join(directoryPath, fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	filePaths := []string{}
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		filePaths = append(filePaths, f.Name)
+	}
+	return filePaths, nil
+}
+
+// GetArchiveChecksums returns the lowercase hex encoded CRC32 checksums of all the files inside a
+// zip archive in the given directory. The checksums are read from the archive's central directory
+// so none of the files have to be decompressed.
+func (m *FileManager) GetArchiveChecksums(directoryPath, fileName string) ([]string, error) {
+	archive, err := zip.OpenReader(filepath.Join(directoryPath, fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	checksums := []string{}
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		checksums = append(checksums, fmt.Sprintf("%08x", f.CRC32))
+	}
+	return checksums, nil
+}
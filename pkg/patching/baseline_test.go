@@ -0,0 +1,217 @@
+package patching
+
+// This file is a frozen copy of matchRomSets and its match selection from before the matcher chain
+// replaced the nested loops. It is the reference the matcher chain is compared with so it must not
+// be changed, apart from the renames needed for it to live beside the real implementation.
+
+import (
+	"sort"
+	"strings"
+)
+
+// baselineMatchTypeRanks orders the match types from best to worst
+var baselineMatchTypeRanks = map[matchType]int{
+	MatchTypeExact:     0,
+	MatchTypeDat:       1,
+	MatchTypeAlternate: 2,
+	MatchTypeFuzzy:     3,
+	MatchTypeScored:    4,
+}
+
+// baselineMatchRomSets will attempt to match a config file to one of the ROMs preferring exact matches,
+// followed by matches on the ROM's canonical name from the DAT, then alternate matches, then fuzzy
+// matches and finally scored matches if they are enabled. When several configs match the same ROM
+// only the best match is kept. ROMs with an override are never matched automatically.
+func (p *Patcher) baselineMatchRomSets(configFiles, romSet []*Rom, overrides *Overrides, canonical map[*Rom]*Rom, scored bool) []*match {
+	overridden := overrideMatches(configFiles, romSet, overrides)
+
+	matches := []*match{}
+	for _, configFile := range configFiles {
+	matchLoop:
+		for _, rom := range romSet {
+			if _, ok := overridden[rom]; ok {
+				continue matchLoop
+			}
+
+			// exactly match roms
+			if configFile.Name == rom.Name {
+				matches = append(matches, &match{
+					configFile: configFile,
+					rom:        rom,
+					matchType:  MatchTypeExact,
+					score:      1,
+					// check if the file names fully match (case-insensitive). This indicates that this match
+					// is an existing match
+					isExisting: strings.ToLower(configFile.FileName) == strings.ToLower(rom.ConfigName()),
+				})
+				continue matchLoop
+			}
+
+			// match the canonical name of badly named roms
+			if baselineIsDatMatch(configFile, canonical[rom]) {
+				matches = append(matches, &match{
+					configFile:    configFile,
+					rom:           rom,
+					matchType:     MatchTypeDat,
+					score:         1,
+					canonicalName: canonical[rom].FileName,
+					isExisting:    strings.EqualFold(configFile.FileName, rom.ConfigName()),
+				})
+				continue matchLoop
+			}
+
+			// try to match rom on alternate name
+			for _, romAlternateName := range rom.AlternateNames {
+				if configFile.Name == romAlternateName {
+					matches = append(matches, &match{
+						configFile: configFile,
+						rom:        rom,
+						matchType:  MatchTypeAlternate,
+						score:      1,
+					})
+					continue matchLoop
+				}
+			}
+
+			// if we still haven't matched anything, attempt to do a fuzzy match
+			for _, romAlternateName := range rom.AlternateNames {
+				for _, configAlternateName := range configFile.AlternateNames {
+					if configAlternateName == romAlternateName {
+						matches = append(matches, &match{
+							configFile: configFile,
+							rom:        rom,
+							matchType:  MatchTypeFuzzy,
+							score:      1,
+						})
+						continue matchLoop
+					}
+				}
+			}
+
+			// finally, score how similar the names are. this is the slowest match so it is only done when asked for
+			if scored {
+				if score := bestSimilarity(configFile, rom); score >= p.scoreThreshold() {
+					matches = append(matches, &match{
+						configFile: configFile,
+						rom:        rom,
+						matchType:  MatchTypeScored,
+						score:      score,
+					})
+				}
+			}
+		}
+	}
+
+	matches = p.baselineSelectBestMatches(matches)
+
+	// keep the ROM set order for the overrides so the matches are stable
+	for _, rom := range romSet {
+		if match, ok := overridden[rom]; ok {
+			matches = append(matches, match)
+		}
+	}
+
+	// record a no match for any ROMs which did not get matched
+romSetLoop:
+	for _, rom := range romSet {
+		for _, match := range matches {
+			if match.rom == rom {
+				continue romSetLoop
+			}
+		}
+		matches = append(matches, &match{
+			rom:       rom,
+			matchType: MatchTypeNone,
+		})
+	}
+
+	// record a no match for any config files which did not get matched
+configSetLoop:
+	for _, config := range configFiles {
+		for _, match := range matches {
+			if match.configFile == config && match.matchType != MatchTypeNone {
+				continue configSetLoop
+			}
+			// configs which lost out to a better match still have a ROM
+			for _, alternative := range match.alternatives {
+				if alternative.configFile == config {
+					continue configSetLoop
+				}
+			}
+		}
+		matches = append(matches, &match{
+			configFile: config,
+			matchType:  MatchTypeNone,
+		})
+	}
+
+	return matches
+}
+
+// baselineIsDatMatch returns true if the config matches the canonical name of the ROM. The full canonical
+// name, including tags, is tried first before falling back to the alternate names of its title.
+func baselineIsDatMatch(configFile, canonical *Rom) bool {
+	if canonical == nil {
+		return false
+	}
+	if strings.EqualFold(trimExtension(configFile.FileName), canonical.FileName) {
+		return true
+	}
+	for _, alternateName := range canonical.AlternateNames {
+		if configFile.Name == alternateName {
+			return true
+		}
+	}
+	return false
+}
+
+// baselineSelectBestMatches reduces the matches to a single match per ROM. The losing matches are kept on
+// the winning match as alternatives so they can be reported.
+func (p *Patcher) baselineSelectBestMatches(matches []*match) []*match {
+	candidates := map[*Rom][]*match{}
+	for _, match := range matches {
+		candidates[match.rom] = append(candidates[match.rom], match)
+	}
+
+	selected := []*match{}
+	for _, match := range matches {
+		romCandidates, ok := candidates[match.rom]
+		if !ok {
+			// the best match for this ROM has already been selected
+			continue
+		}
+		delete(candidates, match.rom)
+
+		sort.SliceStable(romCandidates, func(i, j int) bool {
+			return p.baselineIsBetterMatch(romCandidates[i], romCandidates[j])
+		})
+		best := romCandidates[0]
+		best.alternatives = romCandidates[1:]
+		selected = append(selected, best)
+	}
+	return selected
+}
+
+// baselineIsBetterMatch returns true if match a should be used over match b. Better match types always win,
+// followed by more similar names, configs which already belong to the ROM, configs from the same region
+// as the ROM, configs from a higher priority region and finally the config name so the result is always
+// the same.
+func (p *Patcher) baselineIsBetterMatch(a, b *match) bool {
+	if baselineMatchTypeRanks[a.matchType] != baselineMatchTypeRanks[b.matchType] {
+		return baselineMatchTypeRanks[a.matchType] < baselineMatchTypeRanks[b.matchType]
+	}
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if a.isExisting != b.isExisting {
+		return a.isExisting
+	}
+	aSameRegion, bSameRegion := sharesRegion(a.configFile, a.rom), sharesRegion(b.configFile, b.rom)
+	if aSameRegion != bSameRegion {
+		return aSameRegion
+	}
+	if aRank, bRank := p.regionRank(a.configFile), p.regionRank(b.configFile); aRank != bRank {
+		return aRank < bRank
+	}
+	return strings.ToLower(a.configFile.FileName) < strings.ToLower(b.configFile.FileName)
+}
@@ -0,0 +1,317 @@
+package patching
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Dat is a No-Intro or Redump DAT which lists the canonical name of every known dump for a system
+type Dat struct {
+	Name  string
+	Games []DatGame
+
+	byName map[string]*DatGame
+	byCRC  map[string]*DatGame
+	bySHA1 map[string]*DatGame
+}
+
+// DatGame is a single game in a DAT. Its name is the canonical name used by No-Intro and Redump
+// and so by the Bezel Project configs.
+type DatGame struct {
+	Name string
+	Roms []DatRom
+}
+
+// DatRom is a single file belonging to a game in a DAT. Checksums are lowercase hex.
+type DatRom struct {
+	Name string
+	CRC  string
+	SHA1 string
+}
+
+// datFile is the Logiqx XML format. MAME based DATs use machines rather than games.
+type datFile struct {
+	Header struct {
+		Name string `xml:"name"`
+	} `xml:"header"`
+	Games []struct {
+		Name string `xml:"name,attr"`
+		Roms []struct {
+			Name string `xml:"name,attr"`
+			CRC  string `xml:"crc,attr"`
+			SHA1 string `xml:"sha1,attr"`
+		} `xml:"rom"`
+	} `xml:"game"`
+	Machines []struct {
+		Name string `xml:"name,attr"`
+		Roms []struct {
+			Name string `xml:"name,attr"`
+			CRC  string `xml:"crc,attr"`
+			SHA1 string `xml:"sha1,attr"`
+		} `xml:"rom"`
+	} `xml:"machine"`
+}
+
+// LoadDat reads a DAT file in either the Logiqx XML or ClrMamePro format
+func LoadDat(path string) (*Dat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dat, err := ParseDat(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DAT %s: %w", filepath.Base(path), err)
+	}
+	return dat, nil
+}
+
+// ParseDat parses a DAT in either the Logiqx XML or ClrMamePro format. The format is detected from
+// the contents as both formats commonly use the .dat extension.
+func ParseDat(data []byte) (*Dat, error) {
+	var dat *Dat
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		dat, err = parseLogiqxDat(data)
+	} else {
+		dat, err = parseClrMameProDat(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(dat.Games) == 0 {
+		return nil, errors.New("DAT does not contain any games")
+	}
+	dat.index()
+	return dat, nil
+}
+
+// parseLogiqxDat parses a Logiqx XML DAT
+func parseLogiqxDat(data []byte) (*Dat, error) {
+	file := &datFile{}
+	if err := xml.Unmarshal(data, file); err != nil {
+		return nil, err
+	}
+
+	dat := &Dat{Name: file.Header.Name}
+	for _, game := range file.Games {
+		datGame := DatGame{Name: game.Name}
+		for _, rom := range game.Roms {
+			datGame.Roms = append(datGame.Roms, DatRom{Name: rom.Name, CRC: rom.CRC, SHA1: rom.SHA1})
+		}
+		dat.Games = append(dat.Games, datGame)
+	}
+	for _, machine := range file.Machines {
+		datGame := DatGame{Name: machine.Name}
+		for _, rom := range machine.Roms {
+			datGame.Roms = append(datGame.Roms, DatRom{Name: rom.Name, CRC: rom.CRC, SHA1: rom.SHA1})
+		}
+		dat.Games = append(dat.Games, datGame)
+	}
+	return dat, nil
+}
+
+// parseClrMameProDat parses a ClrMamePro DAT i.e.
+//
+//	game (
+//		name "Super Mario 64 (USA)"
+//		rom ( name "Super Mario 64 (USA).z64" size 8388608 crc 635a2bff sha1 9bef1128... )
+//	)
+func parseClrMameProDat(data []byte) (*Dat, error) {
+	tokens, err := tokenizeClrMamePro(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	dat := &Dat{}
+	for i := 0; i < len(tokens); {
+		if i+1 >= len(tokens) || tokens[i+1] != "(" {
+			return nil, fmt.Errorf("expected a block after %q", tokens[i])
+		}
+		name := tokens[i]
+		block, end, err := clrMameProBlock(tokens, i+2)
+		if err != nil {
+			return nil, err
+		}
+		i = end
+
+		switch strings.ToLower(name) {
+		case "clrmamepro":
+			dat.Name = block.values["name"]
+		case "game", "machine", "resource":
+			game := DatGame{Name: block.values["name"]}
+			for _, rom := range block.blocks["rom"] {
+				game.Roms = append(game.Roms, DatRom{Name: rom.values["name"], CRC: rom.values["crc"], SHA1: rom.values["sha1"]})
+			}
+			dat.Games = append(dat.Games, game)
+		}
+	}
+	return dat, nil
+}
+
+// clrMameProEntry is a parsed ClrMamePro block. Nested blocks are grouped by name.
+type clrMameProEntry struct {
+	values map[string]string
+	blocks map[string][]clrMameProEntry
+}
+
+// clrMameProBlock parses the block starting at the given token and returns it along with the index
+// of the token after the block's closing bracket
+func clrMameProBlock(tokens []string, i int) (clrMameProEntry, int, error) {
+	entry := clrMameProEntry{values: map[string]string{}, blocks: map[string][]clrMameProEntry{}}
+	for i < len(tokens) {
+		key := strings.ToLower(tokens[i])
+		if key == ")" {
+			return entry, i + 1, nil
+		}
+		if i+1 >= len(tokens) {
+			break
+		}
+		if tokens[i+1] == "(" {
+			block, end, err := clrMameProBlock(tokens, i+2)
+			if err != nil {
+				return entry, 0, err
+			}
+			entry.blocks[key] = append(entry.blocks[key], block)
+			i = end
+			continue
+		}
+		entry.values[key] = tokens[i+1]
+		i += 2
+	}
+	return entry, 0, errors.New("unexpected end of DAT, missing )")
+}
+
+// tokenizeClrMamePro splits a ClrMamePro DAT into brackets, words and quoted strings
+func tokenizeClrMamePro(data string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := strings.IndexByte(data[i+1:], '"')
+			if end < 0 {
+				return nil, errors.New("unexpected end of DAT, missing closing quote")
+			}
+			tokens = append(tokens, data[i+1:i+1+end])
+			i += end + 2
+		default:
+			start := i
+			for i < len(data) && !unicode.IsSpace(rune(data[i])) && data[i] != '(' && data[i] != ')' {
+				i++
+			}
+			tokens = append(tokens, data[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+// index builds the lookups used to resolve ROMs. Names are looked up case-insensitively and
+// checksums are stored lowercase.
+func (d *Dat) index() {
+	d.byName = map[string]*DatGame{}
+	d.byCRC = map[string]*DatGame{}
+	d.bySHA1 = map[string]*DatGame{}
+	for i := range d.Games {
+		game := &d.Games[i]
+		d.byName[strings.ToLower(game.Name)] = game
+		for j := range game.Roms {
+			rom := &game.Roms[j]
+			rom.CRC = strings.ToLower(rom.CRC)
+			rom.SHA1 = strings.ToLower(rom.SHA1)
+			if rom.Name != "" {
+				d.byName[strings.ToLower(trimExtension(rom.Name))] = game
+			}
+			if rom.CRC != "" {
+				d.byCRC[rom.CRC] = game
+			}
+			if rom.SHA1 != "" {
+				d.bySHA1[rom.SHA1] = game
+			}
+		}
+	}
+}
+
+// WithDat makes the patcher resolve each ROM to its canonical name in the DAT before matching. ROMs
+// are looked up by file name, including the names of the files in archives when archives are
+// inspected, and also by checksum if ROM hashing is enabled.
+func WithDat(dat *Dat) Option {
+	return func(p *Patcher) {
+		p.dat = dat
+	}
+}
+
+// WithRomHashing makes the patcher look ROMs up in the DAT by their CRC32 and SHA-1 checksums so
+// badly named ROMs can still be resolved. Hashing is slow so it is only done when asked for and
+// has no effect without a DAT.
+func WithRomHashing() Option {
+	return func(p *Patcher) {
+		p.hashRoms = true
+	}
+}
+
+// resolveCanonicalNames looks every ROM up in the DAT and records the canonical names of the ROMs
+// which were found, returning how many were resolved. Checksums are preferred over names as they
+// identify the dump exactly.
+func (p *Patcher) resolveCanonicalNames(romDirPath string, roms []*Rom) int {
+	if p.dat == nil {
+		return 0
+	}
+
+	// hashing is slow so the lookups are shared between the workers
+	games := make([]*DatGame, len(roms))
+	p.forEach(len(roms), func(i int) {
+		games[i] = p.lookupDat(romDirPath, roms[i])
+	})
+
+	resolved := 0
+	for i, rom := range roms {
+		// ROMs which are already correctly named are matched as normal
+		if games[i] != nil && !strings.EqualFold(games[i].Name, trimExtension(rom.FileName)) {
+			rom.CanonicalName = games[i].Name
+			resolved++
+		}
+	}
+	return resolved
+}
+
+// lookupDat returns the game in the DAT which the ROM is a dump of, or nil if it is not in the DAT.
+// Zip archives are looked up by the CRC32 of the files inside them. ROMs which cannot be hashed are
+// still looked up by name.
+func (p *Patcher) lookupDat(romDirPath string, rom *Rom) *DatGame {
+	if p.hashRoms && isZipArchive(rom.FileName) {
+		// DATs list the checksums of the ROMs inside an archive, never of the archive itself
+		if checksums, err := p.fileManager.GetArchiveChecksums(romDirPath, rom.Path); err == nil {
+			for _, crc := range checksums {
+				if game, ok := p.dat.byCRC[crc]; ok {
+					return game
+				}
+			}
+		}
+	} else if p.hashRoms {
+		if crc, sha1, err := p.fileManager.HashFile(romDirPath, rom.Path); err == nil {
+			if game, ok := p.dat.bySHA1[sha1]; ok {
+				return game
+			}
+			if game, ok := p.dat.byCRC[crc]; ok {
+				return game
+			}
+		}
+	}
+	for _, fileName := range append([]string{rom.FileName}, rom.ArchiveContents...) {
+		if game, ok := p.dat.byName[strings.ToLower(trimExtension(fileName))]; ok {
+			return game
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package patching
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/files"
+)
+
+func TestParseDat(t *testing.T) {
+	expectedGames := []DatGame{
+		{Name: "Super Mario 64 (USA)", Roms: []DatRom{{Name: "Super Mario 64 (USA).z64", CRC: "635a2bff", SHA1: "9bef1128717f958171a4afac3ed78ee2bb4e86ce"}}},
+		{Name: "Dr. Mario 64 (USA)", Roms: []DatRom{{Name: "Dr. Mario 64 (USA).z64", CRC: "769d4d13"}}},
+	}
+
+	tt := map[string]string{
+		"logiqx xml": `<?xml version="1.0"?>
+<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">
+<datafile>
+	<header>
+		<name>Nintendo - Nintendo 64 (BigEndian)</name>
+	</header>
+	<game name="Super Mario 64 (USA)">
+		<description>Super Mario 64 (USA)</description>
+		<rom name="Super Mario 64 (USA).z64" size="8388608" crc="635A2BFF" sha1="9BEF1128717F958171A4AFAC3ED78EE2BB4E86CE"/>
+	</game>
+	<game name="Dr. Mario 64 (USA)">
+		<description>Dr. Mario 64 (USA)</description>
+		<rom name="Dr. Mario 64 (USA).z64" size="4194304" crc="769D4D13"/>
+	</game>
+</datafile>`,
+		"clrmamepro": `clrmamepro (
+	name "Nintendo - Nintendo 64 (BigEndian)"
+	description "Nintendo - Nintendo 64 (BigEndian)"
+)
+
+game (
+	name "Super Mario 64 (USA)"
+	description "Super Mario 64 (USA)"
+	rom ( name "Super Mario 64 (USA).z64" size 8388608 crc 635A2BFF sha1 9BEF1128717F958171A4AFAC3ED78EE2BB4E86CE )
+)
+
+game (
+	name "Dr. Mario 64 (USA)"
+	rom ( name "Dr. Mario 64 (USA).z64" size 4194304 crc 769D4D13 )
+)`,
+	}
+
+	for name, contents := range tt {
+		t.Run(name, func(t *testing.T) {
+			dat, err := ParseDat([]byte(contents))
+			require.NoError(t, err)
+			assert.Equal(t, "Nintendo - Nintendo 64 (BigEndian)", dat.Name)
+			assert.Equal(t, expectedGames, dat.Games)
+
+			// games can be found by their name, their ROM names and their checksums
+			assert.Equal(t, "Dr. Mario 64 (USA)", dat.byName["dr. mario 64 (usa)"].Name)
+			assert.Equal(t, "Super Mario 64 (USA)", dat.byName["super mario 64 (usa)"].Name)
+			assert.Equal(t, "Super Mario 64 (USA)", dat.byCRC["635a2bff"].Name)
+			assert.Equal(t, "Super Mario 64 (USA)", dat.bySHA1["9bef1128717f958171a4afac3ed78ee2bb4e86ce"].Name)
+		})
+	}
+}
+
+func TestParseInvalidDat(t *testing.T) {
+	tt := map[string]string{
+		"no games":          `clrmamepro ( name "empty" )`,
+		"unclosed block":    `game ( name "Super Mario 64 (USA)"`,
+		"unclosed quote":    `game ( name "Super Mario 64 (USA) )`,
+		"missing block":     `game name`,
+		"invalid xml":       `<datafile><game name="Super Mario 64 (USA)"></datafile>`,
+		"empty xml datfile": `<datafile></datafile>`,
+	}
+
+	for name, contents := range tt {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDat([]byte(contents))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestZippedRomsAreResolvedByChecksum(t *testing.T) {
+	romDirectoryPath := t.TempDir()
+	romContents := []byte("conker")
+
+	// a badly named ROM inside a badly named archive can only be found by its checksum
+	archive, err := os.Create(filepath.Join(romDirectoryPath, "cbfd.zip"))
+	require.NoError(t, err)
+	writer := zip.NewWriter(archive)
+	entry, err := writer.Create("rom.n64")
+	require.NoError(t, err)
+	_, err = entry.Write(romContents)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, archive.Close())
+
+	dat, err := ParseDat([]byte(fmt.Sprintf(`<datafile>
+	<game name="Conker's Bad Fur Day (USA)"><rom name="Conker's Bad Fur Day (USA).z64" crc="%08x"/></game>
+</datafile>`, crc32.ChecksumIEEE(romContents))))
+	require.NoError(t, err)
+
+	patcher := NewPatcher(&files.FileManager{}, false, WithDat(dat), WithRomHashing())
+	roms := []*Rom{NewRom("cbfd.zip")}
+	assert.Equal(t, 1, patcher.resolveCanonicalNames(romDirectoryPath, roms))
+	assert.Equal(t, "Conker's Bad Fur Day (USA)", roms[0].CanonicalName)
+}
@@ -0,0 +1,91 @@
+package patching
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// defaultExcludedExtensions are extensions of files commonly found alongside ROMs which are never
+// ROMs themselves, i.e. saves, save states, scraped media and metadata
+var defaultExcludedExtensions = []string{
+	// saves and save states
+	".srm", ".sav", ".state", ".auto", ".mcr", ".eep", ".fla", ".rtc", ".nv", ".hi", ".bak",
+	// metadata and config
+	".txt", ".nfo", ".xml", ".dat", ".db", ".ini", ".cfg", ".opt", ".rmp", ".json", ".log",
+	".md5", ".sha1", ".sfv", ".url", ".lnk", ".pdf",
+	// scraped media
+	".png", ".jpg", ".jpeg", ".gif", ".bmp", ".mp4", ".avi", ".mkv",
+}
+
+// WithExcludedExtensions ignores any ROM files with one of the given extensions. Extensions are not
+// case-sensitive and may be given with or without the leading ".".
+func WithExcludedExtensions(extensions ...string) Option {
+	return func(p *Patcher) {
+		p.excludedExtensions = normaliseExtensions(extensions)
+	}
+}
+
+// WithoutDefaultExclusions stops the patcher from ignoring files which are known not to be ROMs,
+// such as saves and scraped media
+func WithoutDefaultExclusions() Option {
+	return func(p *Patcher) {
+		p.disableDefaultExclusions = true
+	}
+}
+
+// ignoreReason returns why the given ROM should be ignored, or an empty string if it is a ROM
+func (p *Patcher) ignoreReason(romDirPath string, rom *Rom) string {
+	extension := romExtension(rom.FileName)
+
+	// explicitly listed extensions always take priority over everything else
+	if containsItem(p.excludedExtensions, extension) {
+		return "excluded extension"
+	}
+	if len(p.includedExtensions) > 0 {
+		if !containsItem(p.includedExtensions, extension) {
+			return "not an included extension"
+		}
+		return ""
+	}
+
+	if p.disableDefaultExclusions {
+		return ""
+	}
+	if strings.HasPrefix(rom.FileName, ".") {
+		return "hidden file"
+	}
+	// recursive scans never include directories so only the top level has to be checked
+	if !p.recursive && p.fileManager.IsDirectory(romDirPath, rom.Path) {
+		return "directory"
+	}
+	if containsItem(defaultExcludedExtensions, extension) {
+		return "not a ROM"
+	}
+	return ""
+}
+
+// romExtension returns the lower case extension of a ROM file. Numbered save state slots
+// (.state1, .state2 etc) are all treated as .state.
+func romExtension(fileName string) string {
+	extension := strings.ToLower(filepath.Ext(fileName))
+	if strings.HasPrefix(extension, ".state") && strings.Trim(extension[len(".state"):], "0123456789") == "" {
+		return ".state"
+	}
+	return extension
+}
+
+// normaliseExtensions lower cases the given extensions and makes sure they start with a "."
+func normaliseExtensions(extensions []string) []string {
+	normalised := []string{}
+	for _, extension := range extensions {
+		extension = strings.ToLower(strings.TrimSpace(extension))
+		if extension == "" {
+			continue
+		}
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		normalised = append(normalised, extension)
+	}
+	return uniqueItems(normalised)
+}
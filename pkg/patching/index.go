@@ -0,0 +1,65 @@
+package patching
+
+import (
+	"strings"
+)
+
+// ConfigIndex looks configs up by every name they can be matched on so each ROM is only compared
+// with the configs it could possibly match, rather than with every config in the set
+type ConfigIndex struct {
+	// configs are every config which can be matched, in their original order
+	configs   []*Rom
+	positions map[*Rom]int
+
+	byName          map[string][]*Rom
+	byAlternateName map[string][]*Rom
+	byFileName      map[string][]*Rom
+}
+
+// NewConfigIndex indexes the configs by their names, their alternate names and their file names
+func NewConfigIndex(configFiles []*Rom) *ConfigIndex {
+	index := &ConfigIndex{
+		configs:         configFiles,
+		positions:       map[*Rom]int{},
+		byName:          map[string][]*Rom{},
+		byAlternateName: map[string][]*Rom{},
+		byFileName:      map[string][]*Rom{},
+	}
+	for i, configFile := range configFiles {
+		index.positions[configFile] = i
+		index.byName[configFile.Name] = append(index.byName[configFile.Name], configFile)
+		for _, alternateName := range uniqueItems(configFile.AlternateNames) {
+			index.byAlternateName[alternateName] = append(index.byAlternateName[alternateName], configFile)
+		}
+		fileName := strings.ToLower(trimExtension(configFile.FileName))
+		index.byFileName[fileName] = append(index.byFileName[fileName], configFile)
+	}
+	return index
+}
+
+// All returns every config in the index in their original order
+func (i *ConfigIndex) All() []*Rom {
+	return i.configs
+}
+
+// ByName returns the configs with the given name i.e. the title without any tags
+func (i *ConfigIndex) ByName(name string) []*Rom {
+	return i.byName[name]
+}
+
+// ByAlternateName returns the configs which have the given name as one of their alternate names
+func (i *ConfigIndex) ByAlternateName(name string) []*Rom {
+	return i.byAlternateName[name]
+}
+
+// ByFileName returns the configs with the given file name, including tags but not the extension.
+// File names are not case-sensitive.
+func (i *ConfigIndex) ByFileName(fileName string) []*Rom {
+	return i.byFileName[strings.ToLower(fileName)]
+}
+
+// position returns where the config is in the original order, or false if it is not in the index
+func (i *ConfigIndex) position(configFile *Rom) (int, bool) {
+	position, ok := i.positions[configFile]
+	return position, ok
+}
@@ -0,0 +1,173 @@
+package patching
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var syntheticWords = []string{
+	"super", "mario", "kart", "wave", "race", "golden", "eye", "star", "fox", "zelda", "legend", "ocarina",
+	"time", "mask", "donkey", "kong", "banjo", "kazooie", "tooie", "perfect", "dark", "rogue", "squadron",
+	"turok", "dinosaur", "hunter", "conker", "bad", "fur", "day", "paper", "party", "tennis", "golf",
+	"pilot", "wings", "blast", "corps", "diddy", "yoshi", "story", "jet", "force", "gemini", "mystical",
+	"ninja", "goemon", "harvest", "moon", "fighter", "quest", "knight", "dragon", "world", "tour",
+}
+
+var (
+	syntheticConfigRegions = []string{"(USA)", "(Europe)", "(Japan)", "(USA, Europe)", "(Europe) (En,Fr,De)", "(USA) (Rev 1)"}
+	syntheticRomRegions    = []string{"(U)", "(E)", "(J)", "(USA)", "(U) [!]", "(E) [b1]", "(U) [T+Spa]", "(Europe)"}
+)
+
+// syntheticTitle builds a random title which is sometimes formatted like a No-Intro title i.e. with
+// the "the" moved to the end, a subtitle or an apostrophe
+func syntheticTitle(r *rand.Rand) string {
+	words := []string{}
+	for i := 0; i < 2+r.Intn(3); i++ {
+		words = append(words, syntheticWords[r.Intn(len(syntheticWords))])
+	}
+	title := strings.Join(words, " ")
+	switch r.Intn(8) {
+	case 0:
+		title = "The " + title
+	case 1:
+		title += ", The"
+	case 2:
+		title += " - " + syntheticWords[r.Intn(len(syntheticWords))]
+	case 3:
+		title = strings.Replace(title, " ", " & ", 1)
+	case 4:
+		title += fmt.Sprintf(" %d", r.Intn(100))
+	case 5:
+		title = strings.Replace(title, " ", "'s ", 1)
+	}
+	return title
+}
+
+// syntheticSets builds configs and ROMs which share some of their titles so every tier of the match
+// ladder is used, and resolves some of the ROMs to a canonical name
+func syntheticSets(size int) ([]*Rom, []*Rom) {
+	r := rand.New(rand.NewSource(int64(size)))
+	configFiles := make([]*Rom, size)
+	romSet := make([]*Rom, size)
+	for i := 0; i < size; i++ {
+		configFiles[i] = NewRom(syntheticTitle(r) + " " + syntheticConfigRegions[r.Intn(len(syntheticConfigRegions))] + ".cfg")
+
+		// most ROMs share a title with one of the configs
+		title := syntheticTitle(r)
+		if i > 0 && r.Intn(4) > 0 {
+			title = configFiles[r.Intn(i)].Title
+			switch r.Intn(4) {
+			case 0:
+				if strings.HasSuffix(title, ", The") {
+					title = "The " + strings.TrimSuffix(title, ", The")
+				}
+			case 1:
+				title = strings.ReplaceAll(title, " - ", " ")
+			case 2:
+				title = strings.ReplaceAll(title, "'", "")
+			}
+		}
+		romSet[i] = NewRom(title + " " + syntheticRomRegions[r.Intn(len(syntheticRomRegions))] + ".n64")
+
+		// some badly named ROMs can be resolved through the DAT
+		if i > 0 && r.Intn(20) == 0 {
+			romSet[i].CanonicalName = trimExtension(configFiles[r.Intn(i)].FileName)
+		}
+	}
+	return configFiles, romSet
+}
+
+// canonicalRoms builds the canonical ROMs the original matchRomSets was given from the canonical
+// names resolved for the ROMs
+func canonicalRoms(romSet []*Rom) map[*Rom]*Rom {
+	canonical := map[*Rom]*Rom{}
+	for _, rom := range romSet {
+		if rom.CanonicalName != "" {
+			canonical[rom] = NewRom(rom.CanonicalName)
+		}
+	}
+	return canonical
+}
+
+// describeMatches describes the matches so they can be compared
+func describeMatches(matches []*match) []string {
+	descriptions := []string{}
+	for _, match := range matches {
+		configFile, rom := "", ""
+		if match.configFile != nil {
+			configFile = match.configFile.FileName
+		}
+		if match.rom != nil {
+			rom = match.rom.FileName
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s -> %s %s %.4f %t %v", rom, configFile, match.matchType, match.score, match.isExisting, match.alternativeNames()))
+	}
+	return descriptions
+}
+
+func TestIndexedMatchingMatchesNestedLoops(t *testing.T) {
+	// scoring compares every config with every ROM so it uses a smaller set
+	for matchFlag, size := range map[matchType]int{MatchTypeFuzzy: 2000, MatchTypeScored: 300} {
+		t.Run(string(matchFlag), func(t *testing.T) {
+			configFiles, romSet := syntheticSets(size)
+			p := NewPatcher(nil, false, WithJobs(4))
+
+			expectedMatches := p.baselineMatchRomSets(configFiles, romSet, &Overrides{}, canonicalRoms(romSet), matchFlag == MatchTypeScored)
+			actualMatches := p.matchRomSets(configFiles, romSet, &Overrides{}, matchFlag)
+			actual := describeMatches(actualMatches)
+			assert.Equal(t, describeMatches(expectedMatches), actual)
+
+			// the original loops only kept the canonical name on DAT matches, the matcher chain keeps it
+			// on every match of a resolved ROM so it can be reported however the ROM was matched
+			for i, expected := range expectedMatches {
+				switch {
+				case expected.rom == nil || expected.rom.CanonicalName == "":
+					assert.Empty(t, actualMatches[i].canonicalName)
+				case expected.matchType == MatchTypeDat:
+					assert.Equal(t, expected.canonicalName, actualMatches[i].canonicalName)
+				default:
+					assert.Empty(t, expected.canonicalName)
+					assert.Equal(t, expected.rom.CanonicalName, actualMatches[i].canonicalName)
+				}
+			}
+
+			// make sure the synthetic sets actually use the ladder
+			matchTypes := map[string]bool{}
+			for _, description := range actual {
+				for _, ladderType := range []matchType{MatchTypeExact, MatchTypeDat, MatchTypeAlternate, MatchTypeFuzzy} {
+					if strings.Contains(description, " "+string(ladderType)+" ") {
+						matchTypes[string(ladderType)] = true
+					}
+				}
+			}
+			assert.Len(t, matchTypes, 4)
+		})
+	}
+}
+
+func BenchmarkMatchRomSets(b *testing.B) {
+	for _, size := range []int{5000, 50000} {
+		configFiles, romSet := syntheticSets(size)
+		p := NewPatcher(nil, false)
+
+		b.Run(fmt.Sprintf("indexed/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p.matchRomSets(configFiles, romSet, &Overrides{}, MatchTypeFuzzy)
+			}
+		})
+
+		// comparing every config with every ROM is far too slow for the larger set
+		if size <= 5000 {
+			canonical := canonicalRoms(romSet)
+			b.Run(fmt.Sprintf("nested/%d", size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					p.baselineMatchRomSets(configFiles, romSet, &Overrides{}, canonical, false)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package patching
+
+import (
+	"fmt"
+	"strings"
+)
+
+// linkMode is used to identify how a new config file is produced from its source config
+type linkMode string
+
+const (
+	// LinkModeCopy copies the source config into a new file
+	LinkModeCopy linkMode = "copy"
+	// LinkModeSymlink creates a symbolic link to the source config
+	LinkModeSymlink linkMode = "symlink"
+	// LinkModeHardlink creates a hard link to the source config
+	LinkModeHardlink linkMode = "hardlink"
+)
+
+// WithLinkMode sets how new config files are produced from their source config. Linked files
+// always have the same contents as their source so they stay up to date when the source changes.
+func WithLinkMode(mode linkMode) Option {
+	return func(p *Patcher) {
+		p.linkMode = mode
+	}
+}
+
+// ParseLinkMode returns the link mode with the given name
+func ParseLinkMode(name string) (linkMode, error) {
+	for _, m := range []linkMode{LinkModeCopy, LinkModeSymlink, LinkModeHardlink} {
+		if strings.EqualFold(name, string(m)) {
+			return m, nil
+		}
+	}
+	return LinkModeCopy, fmt.Errorf("unknown link mode: %s", name)
+}
+
+// createConfig produces the new config file for the given operation using the operation's link mode
+func (p *Patcher) createConfig(configDirPath string, op PlanOperation) error {
+	// generated configs have no source to link to
+	if op.MatchType == MatchTypeTemplate {
+		return p.fileManager.WriteFile(configDirPath, op.File, []byte(op.Contents))
+	}
+
+	switch op.LinkMode {
+	case LinkModeSymlink:
+		return p.fileManager.LinkFileWithName(configDirPath, op.Source, op.File, true)
+	case LinkModeHardlink:
+		return p.fileManager.LinkFileWithName(configDirPath, op.Source, op.File, false)
+	default:
+		return p.fileManager.CopyFileWithName(configDirPath, op.Source, op.File)
+	}
+}
+
+// mode returns the link mode the patcher uses
+func (p *Patcher) mode() linkMode {
+	if p.linkMode == "" {
+		return LinkModeCopy
+	}
+	return p.linkMode
+}
+
+// description describes how a file was produced from its source i.e. "copied from"
+func (m linkMode) description() string {
+	switch m {
+	case LinkModeSymlink:
+		return "symlinked to"
+	case LinkModeHardlink:
+		return "hardlinked to"
+	default:
+		return "copied from"
+	}
+}
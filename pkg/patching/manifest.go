@@ -0,0 +1,144 @@
+package patching
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	manifestPrefix = "patch-manifest."
+	manifestSuffix = ".json"
+)
+
+// ErrNoManifest is returned when a config directory has no manifest to work with
+var ErrNoManifest = errors.New("no patch manifest found")
+
+// Manifest is a machine-readable record of every file created by a committed patch run
+type Manifest struct {
+	RunID           string          `json:"run_id"`
+	CreatedAt       time.Time       `json:"created_at"`
+	ConfigDirectory string          `json:"config_directory"`
+	RomDirectory    string          `json:"rom_directory"`
+	Files           []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry records a single file created by a patch run
+type ManifestEntry struct {
+	File      string    `json:"file"`
+	Source    string    `json:"source"`
+	Rom       string    `json:"rom"`
+	MatchType matchType `json:"match_type"`
+	Score     float64   `json:"score,omitempty"`
+	LinkMode  linkMode  `json:"link_mode,omitempty"`
+	Checksum  string    `json:"checksum"`
+}
+
+// LoadManifest loads the manifest for the given run ID from the config directory. If no
+// run ID is given, the most recent manifest is loaded.
+func LoadManifest(configDirPath, runID string) (*Manifest, error) {
+	if runID == "" {
+		runIDs, err := ListManifests(configDirPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(runIDs) == 0 {
+			return nil, ErrNoManifest
+		}
+		runID = runIDs[len(runIDs)-1]
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDirPath, manifestFileName(runID)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for run %s", ErrNoManifest, runID)
+		}
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to read manifest for run %s: %w", runID, err)
+	}
+	return manifest, nil
+}
+
+// ListManifests returns the run IDs of every manifest in the config directory, oldest first
+func ListManifests(configDirPath string) ([]string, error) {
+	entries, err := os.ReadDir(configDirPath)
+	if err != nil {
+		return nil, err
+	}
+	runIDs := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, manifestPrefix) && strings.HasSuffix(name, manifestSuffix) {
+			runIDs = append(runIDs, strings.TrimSuffix(strings.TrimPrefix(name, manifestPrefix), manifestSuffix))
+		}
+	}
+	// run IDs are unix timestamps so sort them numerically rather than alphabetically
+	sort.Slice(runIDs, func(i, j int) bool {
+		if len(runIDs[i]) != len(runIDs[j]) {
+			return len(runIDs[i]) < len(runIDs[j])
+		}
+		return runIDs[i] < runIDs[j]
+	})
+	return runIDs, nil
+}
+
+// save writes the manifest to the config directory, removing it entirely if there are no
+// files left to track
+func (m *Manifest) save() error {
+	path := filepath.Join(m.ConfigDirectory, manifestFileName(m.RunID))
+	if len(m.Files) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// newRunID returns a unique ID for a patch run. IDs are unix timestamps which are bumped along if
+// the config directory already has a run with the same ID, i.e. when several systems sharing a
+// config directory are patched within the same second.
+func newRunID(configDirPath string) string {
+	id := time.Now().Unix()
+	for {
+		runID := strconv.FormatInt(id, 10)
+		if !fileExists(filepath.Join(configDirPath, manifestFileName(runID))) && !fileExists(filepath.Join(configDirPath, logFileName(runID))) {
+			return runID
+		}
+		id++
+	}
+}
+
+// fileExists returns true if anything exists at the given path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// manifestFileName returns the file name used for the manifest of the given run
+func manifestFileName(runID string) string {
+	return manifestPrefix + runID + manifestSuffix
+}
+
+// checksum returns the hex encoded SHA-256 checksum of the given contents
+func checksum(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,244 @@
+package patching
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Matcher finds the configs which match a ROM. Matchers are run in order as a chain and each
+// config is matched to a ROM by the first matcher in the chain which finds it, so earlier matchers
+// should be the most certain. Match may be called for several ROMs at once so it must be safe to
+// call from multiple goroutines.
+type Matcher interface {
+	// Name identifies the matcher. It is used as the match type of every match the matcher finds
+	// so it can also be used as the match level.
+	Name() string
+	// Match returns the configs from the index which match the ROM
+	Match(rom *Rom, index *ConfigIndex) []Candidate
+}
+
+// Candidate is a config which a matcher found for a ROM along with how confident the matcher is
+// in the match, between 0 and 1. Configs which are not in the index are ignored.
+type Candidate struct {
+	Config     *Rom
+	Confidence float64
+}
+
+// ExactMatcher matches ROMs to configs with exactly the same name, minus any tags i.e. (U), [!]
+type ExactMatcher struct{}
+
+// Name returns the exact match type
+func (ExactMatcher) Name() string {
+	return string(MatchTypeExact)
+}
+
+// Match returns the configs with the same name as the ROM
+func (ExactMatcher) Match(rom *Rom, index *ConfigIndex) []Candidate {
+	return certain(index.ByName(rom.Name))
+}
+
+// DatMatcher matches badly named ROMs to configs using the ROM's canonical name from the DAT. The
+// full canonical name, including tags, is tried first before falling back to the alternate names
+// of its title.
+type DatMatcher struct{}
+
+// Name returns the DAT match type
+func (DatMatcher) Name() string {
+	return string(MatchTypeDat)
+}
+
+// Match returns the configs which match the canonical name of the ROM
+func (DatMatcher) Match(rom *Rom, index *ConfigIndex) []Candidate {
+	if rom.CanonicalName == "" {
+		return nil
+	}
+	canonical := rom.articleSet().newRom(rom.CanonicalName)
+	configFiles := index.ByFileName(canonical.FileName)
+	for _, alternateName := range canonical.AlternateNames {
+		configFiles = append(configFiles, index.ByName(alternateName)...)
+	}
+	return certain(configFiles)
+}
+
+// AlternateMatcher matches ROMs to configs whose name is one of the ROM's alternate names
+type AlternateMatcher struct{}
+
+// Name returns the alternate match type
+func (AlternateMatcher) Name() string {
+	return string(MatchTypeAlternate)
+}
+
+// Match returns the configs named after any of the ROM's alternate names
+func (AlternateMatcher) Match(rom *Rom, index *ConfigIndex) []Candidate {
+	configFiles := []*Rom{}
+	for _, alternateName := range rom.AlternateNames {
+		configFiles = append(configFiles, index.ByName(alternateName)...)
+	}
+	return certain(configFiles)
+}
+
+// FuzzyMatcher matches ROMs to configs which share any alternate name with the ROM
+type FuzzyMatcher struct{}
+
+// Name returns the fuzzy match type
+func (FuzzyMatcher) Name() string {
+	return string(MatchTypeFuzzy)
+}
+
+// Match returns the configs which share an alternate name with the ROM
+func (FuzzyMatcher) Match(rom *Rom, index *ConfigIndex) []Candidate {
+	configFiles := []*Rom{}
+	for _, alternateName := range rom.AlternateNames {
+		configFiles = append(configFiles, index.ByAlternateName(alternateName)...)
+	}
+	return certain(configFiles)
+}
+
+// ScoredMatcher matches ROMs to configs whose names are similar enough. Every config has to be
+// compared with the ROM so it is by far the slowest matcher and only runs when its matches would
+// be used. A matcher without a minimum score uses the patcher's minimum score.
+type ScoredMatcher struct {
+	MinimumScore float64
+}
+
+// Name returns the scored match type
+func (ScoredMatcher) Name() string {
+	return string(MatchTypeScored)
+}
+
+// Match returns the configs which are similar enough to the ROM, with their similarity score as
+// the confidence
+func (m ScoredMatcher) Match(rom *Rom, index *ConfigIndex) []Candidate {
+	minimumScore := m.MinimumScore
+	if minimumScore <= 0 {
+		minimumScore = DefaultMinimumScore
+	}
+	candidates := []Candidate{}
+	for _, configFile := range index.All() {
+		if score := bestSimilarity(configFile, rom); score >= minimumScore {
+			candidates = append(candidates, Candidate{Config: configFile, Confidence: score})
+		}
+	}
+	return candidates
+}
+
+// defaultMatchers is the match ladder used when no matchers have been set
+var defaultMatchers = []Matcher{ExactMatcher{}, DatMatcher{}, AlternateMatcher{}, FuzzyMatcher{}, ScoredMatcher{}}
+
+// DefaultMatchers returns the matchers used when no matchers have been set, from the most to the
+// least certain, so custom matchers can be added to them
+func DefaultMatchers() []Matcher {
+	return append([]Matcher{}, defaultMatchers...)
+}
+
+// WithMatchers replaces the patcher's matchers with the given chain. The chain is run in order
+// and the match level must be the name of one of its matchers; matches from any matcher after
+// the match level are skipped.
+func WithMatchers(matchers ...Matcher) Option {
+	return func(p *Patcher) {
+		p.matchers = matchers
+	}
+}
+
+// matcherChain returns the matchers set on the patcher or the default matchers
+func (p *Patcher) matcherChain() []Matcher {
+	if p.matchers == nil {
+		return defaultMatchers
+	}
+	return p.matchers
+}
+
+// checkMatchers makes sure every matcher in the chain has its own match type and that the match
+// level is one of them
+func (p *Patcher) checkMatchers(matchFlag matchType) error {
+	names := map[string]bool{}
+	for _, matcher := range p.matcherChain() {
+		name := matcher.Name()
+		switch matchType(name) {
+		case MatchTypeOverride, MatchTypeTemplate, MatchTypeNone, "":
+			return fmt.Errorf("matcher name %q is reserved", name)
+		}
+		if names[name] {
+			return fmt.Errorf("more than one matcher is named %q", name)
+		}
+		names[name] = true
+	}
+	if !names[string(matchFlag)] {
+		return fmt.Errorf("match level %s is not one of the matchers", matchFlag)
+	}
+	return nil
+}
+
+// activeMatchers returns the matchers which should run at the given match level
+func (p *Patcher) activeMatchers(matchFlag matchType) []Matcher {
+	matchers := []Matcher{}
+	for _, matcher := range p.matcherChain() {
+		if scored, ok := matcher.(ScoredMatcher); ok {
+			// scoring is the slowest match so it is only done when asked for
+			if !p.shouldInclude(matchType(scored.Name()), matchFlag) {
+				continue
+			}
+			if scored.MinimumScore <= 0 {
+				matcher = ScoredMatcher{MinimumScore: p.scoreThreshold()}
+			}
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers
+}
+
+// matchRom runs the ROM through the matchers and returns a match for every config found. Each
+// config is matched by the first matcher which finds it.
+func matchRom(rom *Rom, index *ConfigIndex, matchers []Matcher) []*match {
+	matched := map[*Rom]bool{}
+	matches := []*match{}
+	for _, matcher := range matchers {
+		for _, candidate := range matcher.Match(rom, index) {
+			if _, ok := index.position(candidate.Config); !ok || matched[candidate.Config] {
+				continue
+			}
+			matched[candidate.Config] = true
+			matches = append(matches, &match{
+				configFile:    candidate.Config,
+				rom:           rom,
+				matchType:     matchType(matcher.Name()),
+				score:         candidate.Confidence,
+				canonicalName: rom.CanonicalName,
+				// check if the file names fully match (case-insensitive). This indicates that this
+				// match is an existing match
+				isExisting: strings.EqualFold(candidate.Config.FileName, rom.ConfigName()),
+			})
+		}
+	}
+	return matches
+}
+
+// matchRank returns the position of the match type in the matcher chain, or -1 if no matcher
+// produces it
+func (p *Patcher) matchRank(t matchType) int {
+	for i, matcher := range p.matcherChain() {
+		if matcher.Name() == string(t) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isBuiltInMatchType returns true if the match type is produced by one of the built in matchers
+func isBuiltInMatchType(t matchType) bool {
+	for _, matcher := range defaultMatchers {
+		if matcher.Name() == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// certain returns the configs as candidates the matcher is fully confident in
+func certain(configFiles []*Rom) []Candidate {
+	candidates := make([]Candidate, len(configFiles))
+	for i, configFile := range configFiles {
+		candidates[i] = Candidate{Config: configFile, Confidence: 1}
+	}
+	return candidates
+}
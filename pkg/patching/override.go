@@ -0,0 +1,123 @@
+package patching
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverridesFileName is the file in the config directory which manual overrides are read from
+const OverridesFileName = "patch-overrides.yaml"
+
+// OverrideIgnore is used in place of a config file name to ignore the ROM completely
+const OverrideIgnore = "ignore"
+
+// Override maps a ROM to a specific config, or to OverrideIgnore to ignore the ROM. The ROM may be
+// a file name or a glob pattern i.e. "Mother 3 (J) [T+Eng*].gba". Only * and ? are treated as
+// wildcards as brackets are common in ROM names.
+type Override struct {
+	Rom    string `json:"rom" yaml:"rom"`
+	Config string `json:"config" yaml:"config"`
+
+	pattern *regexp.Regexp
+}
+
+// Overrides is the list of manual overrides for a config directory. The first override which
+// matches a ROM is used.
+type Overrides struct {
+	Overrides []Override `json:"overrides" yaml:"overrides"`
+}
+
+// loadOverrides reads the overrides file from the config directory. A missing file means there are
+// no overrides.
+func (p *Patcher) loadOverrides(configDirPath string) (*Overrides, error) {
+	overrides := &Overrides{}
+	if !p.fileManager.FileExists(configDirPath, OverridesFileName) {
+		return overrides, nil
+	}
+
+	data, err := p.fileManager.ReadFile(configDirPath, OverridesFileName)
+	if err != nil {
+		return nil, err
+	}
+	// YAML is a superset of JSON so either can be used
+	if err := yaml.Unmarshal(data, overrides); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", OverridesFileName, err)
+	}
+	for i := range overrides.Overrides {
+		override := &overrides.Overrides[i]
+		if override.Rom == "" || override.Config == "" {
+			return nil, fmt.Errorf("override %d in %s must have both a rom and a config", i+1, OverridesFileName)
+		}
+		override.pattern = globPattern(override.Rom)
+	}
+	return overrides, nil
+}
+
+// globPattern converts a glob into a case-insensitive regular expression which matches the whole name
+func globPattern(glob string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.MustCompile("(?i)^" + pattern + "$")
+}
+
+// find returns the override for the given ROM, or nil if there is no override. Overrides are
+// matched against the ROM's file name and its path within the ROM directory.
+func (o *Overrides) find(rom *Rom) *Override {
+	for i, override := range o.Overrides {
+		for _, name := range []string{rom.FileName, filepath.ToSlash(rom.Path)} {
+			if override.pattern.MatchString(name) {
+				return &o.Overrides[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ignoredByOverride removes any ROMs which are ignored by an override and records them as ignored files
+func ignoredByOverride(roms []*Rom, overrides *Overrides, ignoredFiles map[string]string) []*Rom {
+	filtered := []*Rom{}
+	for _, rom := range roms {
+		if override := overrides.find(rom); override != nil && strings.EqualFold(override.Config, OverrideIgnore) {
+			ignoredFiles[rom.Path] = "ignored by override"
+			continue
+		}
+		filtered = append(filtered, rom)
+	}
+	return filtered
+}
+
+// overrideMatches builds a match for every ROM with an override. ROMs whose override config does not
+// exist are recorded as unmatched so they are not matched automatically either.
+func overrideMatches(configFiles, romSet []*Rom, overrides *Overrides) map[*Rom]*match {
+	matches := map[*Rom]*match{}
+	for _, rom := range romSet {
+		override := overrides.find(rom)
+		if override == nil {
+			continue
+		}
+
+		matches[rom] = &match{
+			rom:       rom,
+			matchType: MatchTypeNone,
+			err:       fmt.Errorf("override config not found: %s", override.Config),
+		}
+		for _, configFile := range configFiles {
+			if strings.EqualFold(configFile.FileName, override.Config) {
+				matches[rom] = &match{
+					configFile: configFile,
+					rom:        rom,
+					matchType:  MatchTypeOverride,
+					score:      1,
+					isExisting: strings.EqualFold(configFile.FileName, rom.ConfigName()),
+				}
+				break
+			}
+		}
+	}
+	return matches
+}
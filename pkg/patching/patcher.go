@@ -1,6 +1,7 @@
 package patching
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,11 +10,29 @@ import (
 	"time"
 )
 
+// ErrFilesFailed is returned when one or more new configs could not be created
+var ErrFilesFailed = errors.New("failed to create config files")
+
+// errFailFast is recorded against any new configs which were not attempted because fail fast
+// stopped the run at an earlier failure
+var errFailFast = errors.New("not attempted after an earlier failure")
+
 // FileMangerInterface defines the methods required to interact with the file system
 type FileMangerInterface interface {
 	GetDirectoryContents(directoryPath string) ([]string, error)
+	GetDirectoryContentsRecursive(directoryPath string) ([]string, error)
+	GetArchiveContents(directoryPath, fileName string) ([]string, error)
+	GetArchiveChecksums(directoryPath, fileName string) ([]string, error)
 	CopyFileWithName(directoryPath, filePath, newName string) error
+	WriteFile(directoryPath, fileName string, contents []byte) error
+	LinkFileWithName(directoryPath, filePath, newName string, symbolic bool) error
 	FileExists(directoryPath, fileName string) bool
+	LinkExists(directoryPath, fileName string) bool
+	IsDirectory(directoryPath, fileName string) bool
+	ReadFile(directoryPath, fileName string) ([]byte, error)
+	RemoveFile(directoryPath, fileName string) error
+	MoveFile(directoryPath, fileName, newDirectoryPath string) error
+	HashFile(directoryPath, fileName string) (crc, sha1 string, err error)
 }
 
 // matchType is used to identify what match type was used to match 2 file names
@@ -22,50 +41,186 @@ type matchType string
 const (
 	// MatchTypeExact means the ROM and config names (minus any tags i.e. (U), [!]) matched exactly
 	MatchTypeExact matchType = "exact"
+	// MatchTypeDat means the ROM's canonical name from the DAT matched the config name
+	MatchTypeDat matchType = "dat"
 	// MatchTypeAlternate means a ROM's alternate name matched the config name
 	MatchTypeAlternate matchType = "alternate"
 	// MatchTypeFuzzy means a ROM's alternate name matched one of the configs alternate names
 	MatchTypeFuzzy matchType = "fuzzy"
+	// MatchTypeScored means a ROM's alternate name was similar enough to one of the configs alternate names
+	MatchTypeScored matchType = "scored"
+	// MatchTypeOverride means the ROM was matched to the config by an override in the overrides file
+	MatchTypeOverride matchType = "override"
+	// MatchTypeTemplate means no config matched the ROM so one was generated from the fallback template
+	MatchTypeTemplate matchType = "template"
 	// MatchTypeNone means no match was found
 	MatchTypeNone matchType = "none"
 )
 
 // match records a match between a config file and a ROM, what
-// type of match it was and whether it was existing or not i.e the config for the
-// ROM already existed.
+// type of match it was, how similar the names were and whether it was existing or
+// not i.e the config for the ROM already existed.
 type match struct {
 	configFile *Rom
 	rom        *Rom
 	matchType  matchType
+	score      float64
 	isExisting bool
+	// alternatives are the other matches which were considered for the ROM but lost out to this one
+	alternatives []*match
+	// rendered is the contents of a config generated from the fallback template
+	rendered []byte
+	// err is set if the new config for the match could not be created
+	err error
+	// decision is set if the match was reviewed
+	decision decisionAction
+	// canonicalName is the ROM's name in the DAT if it was resolved through the DAT
+	canonicalName string
 }
 
 // Patcher defines the dependencies in order to success patch a directory
 type Patcher struct {
-	fileManager FileMangerInterface
-	commit      bool
+	fileManager      FileMangerInterface
+	commit           bool
+	validateOverlays bool
+	recursive        bool
+	inspectArchives  bool
+
+	includedExtensions       []string
+	excludedExtensions       []string
+	disableDefaultExclusions bool
+
+	regionPriority []string
+	minimumScore   float64
+	linkMode       linkMode
+
+	fallbackTemplate *FallbackTemplate
+	failFast         bool
+	reviewer         Reviewer
+
+	dat      *Dat
+	hashRoms bool
+	jobs     int
+
+	generatedSources bool
+
+	matchers []Matcher
+	articles *articleSet
+}
+
+// Option configures optional Patcher behaviour
+type Option func(*Patcher)
+
+// WithOverlayValidation makes the patcher check each config's overlay chain and skip any
+// configs whose overlay config or overlay image is missing
+func WithOverlayValidation() Option {
+	return func(p *Patcher) {
+		p.validateOverlays = true
+	}
+}
+
+// WithRecursiveScan makes the patcher walk every subdirectory of the ROM directory rather than
+// only looking at the top level
+func WithRecursiveScan() Option {
+	return func(p *Patcher) {
+		p.recursive = true
+	}
+}
+
+// WithArchiveInspection makes the patcher look inside zip archives and use the names of the files
+// they contain as alternate names for the ROM
+func WithArchiveInspection() Option {
+	return func(p *Patcher) {
+		p.inspectArchives = true
+	}
+}
+
+// WithIncludedExtensions restricts the ROMs to files with one of the given extensions. Extensions
+// are not case-sensitive and may be given with or without the leading ".".
+func WithIncludedExtensions(extensions ...string) Option {
+	return func(p *Patcher) {
+		p.includedExtensions = normaliseExtensions(extensions)
+	}
+}
+
+// WithFailFast makes the patcher stop creating configs as soon as one of them cannot be created
+func WithFailFast() Option {
+	return func(p *Patcher) {
+		p.failFast = true
+	}
 }
 
 // NewPatcher returns a new Patcher with the required dependencies
-func NewPatcher(fileManager FileMangerInterface, commit bool) *Patcher {
-	return &Patcher{
+func NewPatcher(fileManager FileMangerInterface, commit bool, opts ...Option) *Patcher {
+	p := &Patcher{
 		fileManager: fileManager,
 		commit:      commit,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// With returns a copy of the patcher with the given options applied on top of its current options
+func (p *Patcher) With(opts ...Option) *Patcher {
+	patcher := *p
+	for _, opt := range opts {
+		opt(&patcher)
+	}
+	return &patcher
+}
+
+// FailsFast returns true if the patcher stops at the first config which cannot be created
+func (p *Patcher) FailsFast() bool {
+	return p.failFast
+}
+
+// ParseMatchType returns the built in match type with the given name so it can be used as a match
+// flag. Use Patcher.ParseMatchType to also accept the names of custom matchers.
+func ParseMatchType(name string) (matchType, error) {
+	for _, t := range []matchType{MatchTypeExact, MatchTypeAlternate, MatchTypeFuzzy, MatchTypeScored} {
+		if strings.EqualFold(name, string(t)) {
+			return t, nil
+		}
+	}
+	return MatchTypeNone, fmt.Errorf("unknown match type: %s", name)
+}
+
+// ParseMatchType returns the match type of the matcher in the patcher's matcher chain with the given
+// name so it can be used as a match flag, including the names of any custom matchers
+func (p *Patcher) ParseMatchType(name string) (matchType, error) {
+	for _, matcher := range p.matcherChain() {
+		if strings.EqualFold(name, matcher.Name()) {
+			return matchType(matcher.Name()), nil
+		}
+	}
+	return MatchTypeNone, fmt.Errorf("unknown match type: %s", name)
 }
 
 // PatchDirectory patches the given config directory with the ROMs in the given ROM directory.
-// New files will only be created if the match type matches the match flag.
-func (p *Patcher) PatchDirectory(configDirPath, romDirPath string, matchFlag matchType) error {
+// New files will only be created if the match type matches the match flag. If any new files could
+// not be created, the result is returned along with ErrFilesFailed.
+func (p *Patcher) PatchDirectory(configDirPath, romDirPath string, matchFlag matchType) (*PatchResult, error) {
+	if err := p.checkMatchers(matchFlag); err != nil {
+		return nil, err
+	}
+	runID := newRunID(configDirPath)
+
 	// get a list of files from the config directory and the rom directory
 	configDirFiles, err := p.fileManager.GetDirectoryContents(configDirPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	roms, ignoredFiles, err := p.scanRomDirectory(romDirPath)
+	if err != nil {
+		return nil, err
 	}
-	romDirFiles, err := p.fileManager.GetDirectoryContents(romDirPath)
+	overrides, err := p.loadOverrides(configDirPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	roms = ignoredByOverride(roms, overrides, ignoredFiles)
 
 	// filter out anything which does not look like a config file
 	filteredConfigDirFiles := []string{}
@@ -77,141 +232,292 @@ func (p *Patcher) PatchDirectory(configDirPath, romDirPath string, matchFlag mat
 
 	configFiles := make([]*Rom, len(filteredConfigDirFiles))
 	for i, item := range filteredConfigDirFiles {
-		configFiles[i] = NewRom(item)
+		configFiles[i] = p.articleSet().newRom(item)
+	}
+	configCount := len(configFiles)
+
+	// configs created by previous runs are copies of an original so they are only used as a source
+	// when asked for
+	generated, err := generatedConfigs(configDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous manifests: %w", err)
+	}
+	originalConfigs, generatedFiles := splitGeneratedConfigs(configFiles, generated)
+	excludedConfigs := map[*Rom]string{}
+	if !p.generatedSources {
+		configFiles, excludedConfigs = originalConfigs, generatedFiles
+	}
+
+	// broken configs are never used as a source so they are not duplicated any further
+	brokenConfigs := map[*Rom]string{}
+	if p.validateOverlays {
+		configFiles, brokenConfigs = p.validateConfigs(configDirPath, configFiles)
+	}
+
+	p.resolveCanonicalNames(romDirPath, roms)
+	matches := p.matchRomSets(configFiles, roms, overrides, matchFlag)
+	if p.fallbackTemplate != nil {
+		p.applyFallbackTemplate(matches, romDirPath)
+	}
+	var decisions map[string]Decision
+	if p.reviewer != nil {
+		if decisions, err = p.reviewMatches(configDirPath, matches, matchFlag); err != nil {
+			return nil, fmt.Errorf("failed to review matches: %w", err)
+		}
 	}
 
-	roms := make([]*Rom, len(romDirFiles))
-	for i, item := range romDirFiles {
-		roms[i] = NewRom(item)
+	manifest := &Manifest{
+		RunID:           runID,
+		CreatedAt:       time.Now(),
+		ConfigDirectory: configDirPath,
+		RomDirectory:    romDirPath,
+		Files:           []ManifestEntry{},
 	}
 
-	matches := p.matchRomSets(configFiles, roms)
+	plan := &Plan{
+		RunID:           runID,
+		CreatedAt:       time.Now(),
+		ConfigDirectory: configDirPath,
+		RomDirectory:    romDirPath,
+		Operations:      []PlanOperation{},
+	}
 
+	planned := map[string]bool{}
+	failedCount := 0
 	for _, match := range matches {
 		if match.matchType != MatchTypeNone {
-			// make sure the file has not already been added (might have been added by
+			// make sure the file has not already been added (might have been added, or planned, by
 			// a previous match so we have to check)
-			if p.fileManager.FileExists(configDirPath, match.rom.ConfigName()) {
+			if planned[strings.ToLower(match.rom.ConfigName())] || p.fileManager.FileExists(configDirPath, match.rom.ConfigName()) {
 				match.isExisting = true
 				continue
 			}
-			if !match.isExisting && shouldInclude(match.matchType, matchFlag) {
+			if !match.isExisting && p.shouldInclude(match.matchType, matchFlag) && isAccepted(match) {
+				op := p.planOperation(match)
+
 				// only do the file operations if --commit was specified. this gives the
 				// users a chance to sanity check the log before changing any of their files
-				if p.commit {
-					p.fileManager.CopyFileWithName(configDirPath, match.configFile.FileName, match.rom.ConfigName())
+				if !p.commit {
+					plan.Operations = append(plan.Operations, op)
+					planned[strings.ToLower(op.File)] = true
+					continue
+				}
+				if p.failFast && failedCount > 0 {
+					match.err = errFailFast
+					continue
 				}
+				// a file which could not be created is left for any other match with the same name
+				if err := p.createConfig(configDirPath, op); err != nil {
+					match.err = err
+					failedCount++
+					continue
+				}
+				manifest.Files = append(manifest.Files, p.manifestEntry(configDirPath, op))
+				plan.Operations = append(plan.Operations, op)
+				planned[strings.ToLower(op.File)] = true
 			}
 		}
 	}
 
-	p.produceLog(len(roms), len(configFiles), romDirPath, configDirPath, matches, matchFlag)
+	result := p.produceResult(runID, len(roms), configCount, romDirPath, configDirPath, matches, brokenConfigs, excludedConfigs, ignoredFiles, matchFlag)
+	result.GeneratedConfigs = len(generatedFiles)
+	result.Plan = plan
+	p.produceLog(result, matchFlag)
+
+	// record exactly which files were created so the run can be rolled back later
+	if p.commit && len(manifest.Files) > 0 {
+		if err := manifest.save(); err != nil {
+			return result, fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+
+	// the decisions are saved last so they only cover the files which were really created
+	if p.reviewer != nil {
+		if err := p.saveReviewDecisions(configDirPath, decisions, matches); err != nil {
+			return result, fmt.Errorf("failed to save review decisions: %w", err)
+		}
+	}
+
+	if failedCount > 0 {
+		return result, fmt.Errorf("%w: %d failed", ErrFilesFailed, failedCount)
+	}
+	return result, nil
+}
+
+// isAccepted returns true if the match was not reviewed or the reviewer accepted it
+func isAccepted(match *match) bool {
+	return match.decision == "" || match.decision == DecisionAccept
+}
 
-	return nil
+// manifestEntry builds the manifest entry for a file which has just been created by the given operation
+func (p *Patcher) manifestEntry(configDirPath string, op PlanOperation) ManifestEntry {
+	entry := ManifestEntry{
+		File:      op.File,
+		Source:    op.Source,
+		Rom:       op.Rom,
+		MatchType: op.MatchType,
+		Score:     op.Score,
+		LinkMode:  op.LinkMode,
+	}
+	if contents, err := p.fileManager.ReadFile(configDirPath, entry.File); err == nil {
+		entry.Checksum = checksum(contents)
+	}
+	return entry
 }
 
-// matchRomSets will attempt to match a config file to one of the ROMs preferring exact matches,
-// followed by alternate matches, then fuzzy matches.
-func (p *Patcher) matchRomSets(configFiles, romSet []*Rom) []*match {
+// matchRomSets runs every ROM through the matcher chain, which by default prefers exact matches,
+// followed by matches on the ROM's canonical name from the DAT, then alternate matches, then fuzzy
+// matches and finally scored matches if they are enabled. When several configs match the same ROM
+// only the best match is kept. ROMs with an override are never matched automatically.
+func (p *Patcher) matchRomSets(configFiles, romSet []*Rom, overrides *Overrides, matchFlag matchType) []*match {
+	overridden := overrideMatches(configFiles, romSet, overrides)
+	index := NewConfigIndex(configFiles)
+	matchers := p.activeMatchers(matchFlag)
+
+	// the matchers only look at a single ROM so the ROMs are shared between the workers
+	romMatches := make([][]*match, len(romSet))
+	p.forEach(len(romSet), func(i int) {
+		if _, ok := overridden[romSet[i]]; !ok {
+			romMatches[i] = matchRom(romSet[i], index, matchers)
+		}
+	})
+
 	matches := []*match{}
-	for _, configFile := range configFiles {
-	matchLoop:
-		for _, rom := range romSet {
-			// exactly match roms
-			if configFile.Name == rom.Name {
-				matches = append(matches, &match{
-					configFile: configFile,
-					rom:        rom,
-					matchType:  MatchTypeExact,
-					// check if the file names fully match (case-insensitive). This indicates that this match
-					// is an existing match
-					isExisting: strings.ToLower(configFile.FileName) == strings.ToLower(rom.ConfigName()),
-				})
-				continue matchLoop
-			}
+	for _, m := range romMatches {
+		matches = append(matches, m...)
+	}
+	// keep the config order so the best matches are selected, and reported, in a stable order
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, _ := index.position(matches[i].configFile)
+		b, _ := index.position(matches[j].configFile)
+		return a < b
+	})
 
-			// try to match rom on alternate name
-			for _, romAlternateName := range rom.AlternateNames {
-				if configFile.Name == romAlternateName {
-					matches = append(matches, &match{
-						configFile: configFile,
-						rom:        rom,
-						matchType:  MatchTypeAlternate,
-					})
-					continue matchLoop
-				}
-			}
+	matches = p.selectBestMatches(matches)
 
-			// if we still haven't matched anything, attempt to do a fuzzy match
-			for _, romAlternateName := range rom.AlternateNames {
-				for _, configAlternateName := range configFile.AlternateNames {
-					if configAlternateName == romAlternateName {
-						matches = append(matches, &match{
-							configFile: configFile,
-							rom:        rom,
-							matchType:  MatchTypeFuzzy,
-						})
-						continue matchLoop
-					}
-				}
-			}
+	// keep the ROM set order for the overrides so the matches are stable
+	for _, rom := range romSet {
+		if match, ok := overridden[rom]; ok {
+			matches = append(matches, match)
+		}
+	}
+
+	matchedRoms := map[*Rom]bool{}
+	matchedConfigs := map[*Rom]bool{}
+	for _, match := range matches {
+		matchedRoms[match.rom] = true
+		if match.matchType != MatchTypeNone {
+			matchedConfigs[match.configFile] = true
+		}
+		// configs which lost out to a better match still have a ROM
+		for _, alternative := range match.alternatives {
+			matchedConfigs[alternative.configFile] = true
 		}
 	}
 
 	// record a no match for any ROMs which did not get matched
-romSetLoop:
 	for _, rom := range romSet {
-		for _, match := range matches {
-			if match.rom == rom && match.matchType != MatchTypeNone {
-				continue romSetLoop
-			}
+		if !matchedRoms[rom] {
+			matches = append(matches, &match{
+				rom:       rom,
+				matchType: MatchTypeNone,
+			})
 		}
-		matches = append(matches, &match{
-			rom:       rom,
-			matchType: MatchTypeNone,
-		})
 	}
 
 	// record a no match for any config files which did not get matched
-configSetLoop:
 	for _, config := range configFiles {
-		for _, match := range matches {
-			if match.configFile == config && match.matchType != MatchTypeNone {
-				continue configSetLoop
-			}
+		if !matchedConfigs[config] {
+			matches = append(matches, &match{
+				configFile: config,
+				matchType:  MatchTypeNone,
+			})
 		}
-		matches = append(matches, &match{
-			configFile: config,
-			matchType:  MatchTypeNone,
-		})
 	}
 
 	return matches
 }
 
-// produceLog write a log file to config directory to give a detailed description of what the patching did
-func (p *Patcher) produceLog(romCount, configCount int, romDirPath, configPath string, matches []*match, matchFlag matchType) {
-	romsWithoutConfig := []string{}
-	configWithoutRoms := []string{}
-	createdFiles := map[matchType][]string{}
-	for _, t := range []matchType{MatchTypeExact, MatchTypeAlternate, MatchTypeFuzzy} {
-		createdFiles[t] = []string{}
+// produceResult builds the result of a patch run from its matches
+func (p *Patcher) produceResult(runID string, romCount, configCount int, romDirPath, configPath string, matches []*match, brokenConfigs, generatedConfigs map[*Rom]string, ignoredFiles map[string]string, matchFlag matchType) *PatchResult {
+	result := &PatchResult{
+		RunID:            runID,
+		ConfigDirectory:  configPath,
+		RomDirectory:     romDirPath,
+		ConfigCount:      configCount,
+		RomCount:         romCount,
+		Created:          []ReportEntry{},
+		Skipped:          []ReportEntry{},
+		Existing:         []ReportEntry{},
+		Failed:           []ReportEntry{},
+		Rejected:         []ReportEntry{},
+		UnmatchedRoms:    []string{},
+		UnmatchedConfigs: []string{},
+		Report:           p.produceReport(runID, romDirPath, configPath, matches, brokenConfigs, generatedConfigs, ignoredFiles, matchFlag),
 	}
 
-	for _, match := range matches {
-		if match.matchType == MatchTypeNone {
-			if match.configFile != nil {
-				configWithoutRoms = append(configWithoutRoms, match.configFile.FileName)
+	for _, entry := range result.Report.Entries {
+		switch entry.Status {
+		case ReportStatusCreated:
+			result.Created = append(result.Created, entry)
+		case ReportStatusSkipped:
+			result.Skipped = append(result.Skipped, entry)
+		case ReportStatusExisting:
+			result.Existing = append(result.Existing, entry)
+		case ReportStatusFailed:
+			result.Failed = append(result.Failed, entry)
+		case ReportStatusRejected:
+			result.Rejected = append(result.Rejected, entry)
+		case ReportStatusUnmatched:
+			if entry.ConfigFile != "" {
+				result.UnmatchedConfigs = append(result.UnmatchedConfigs, entry.ConfigFile)
 			} else {
-				romsWithoutConfig = append(romsWithoutConfig, match.rom.FileName)
-			}
-		} else {
-			if match.isExisting {
-				continue
-			}
-			if strings.ToLower(match.rom.ConfigName()) != strings.ToLower(match.configFile.FileName) {
-				createdFiles[match.matchType] = append(createdFiles[match.matchType], fmt.Sprintf("%s -> %s copied from: %s", match.rom.FileName, match.rom.ConfigName(), match.configFile.FileName))
+				result.UnmatchedRoms = append(result.UnmatchedRoms, entry.RomFile)
 			}
+		case ReportStatusBroken:
+			result.BrokenConfigs++
+		case ReportStatusIgnored:
+			result.IgnoredFiles++
+		}
+	}
+	sortAlphabetical(result.UnmatchedRoms)
+	sortAlphabetical(result.UnmatchedConfigs)
+
+	result.MissingRoms = len(result.UnmatchedConfigs)
+	result.MissingConfigs = len(result.UnmatchedRoms)
+	result.CreatedFiles = len(result.Created)
+	result.SkippedFiles = len(result.Skipped)
+	result.FailedFiles = len(result.Failed)
+
+	return result
+}
+
+// produceLog write a log file to config directory to give a detailed description of what the patching did.
+// The log path is recorded on the result.
+func (p *Patcher) produceLog(result *PatchResult, matchFlag matchType) {
+	newFiles := map[matchType][]string{}
+	for _, entry := range result.Created {
+		newFiles[entry.MatchType] = append(newFiles[entry.MatchType], newFileLine(entry))
+	}
+	// files skipped because of the match level are listed with the new files, anything skipped
+	// for another reason is listed separately along with the reason
+	otherSkippedLines := []string{}
+	for _, entry := range result.Skipped {
+		if !p.shouldInclude(entry.MatchType, matchFlag) {
+			newFiles[entry.MatchType] = append(newFiles[entry.MatchType], newFileLine(entry))
+			continue
+		}
+		otherSkippedLines = append(otherSkippedLines, fmt.Sprintf("%s -> %s (%s)", entry.RomFile, entry.NewFile, entry.Reason))
+	}
+
+	brokenConfigLines := []string{}
+	ignoredFileLines := []string{}
+	for _, entry := range result.Report.Entries {
+		switch entry.Status {
+		case ReportStatusBroken:
+			brokenConfigLines = append(brokenConfigLines, fmt.Sprintf("%s (%s)", entry.ConfigFile, entry.Reason))
+		case ReportStatusIgnored:
+			ignoredFileLines = append(ignoredFileLines, fmt.Sprintf("%s (%s)", entry.RomFile, entry.Reason))
 		}
 	}
 
@@ -219,65 +525,145 @@ func (p *Patcher) produceLog(romCount, configCount int, romDirPath, configPath s
 	if !p.commit {
 		log = "[DRY]\n\n"
 	}
-	log += fmt.Sprintf("Found %d config files in: %s\nFound %d roms in: %s\n\n", configCount, configPath, romCount, romDirPath)
-	log += fmt.Sprintf("Missing ROMs: %d\nMissing config: %d\n\n", len(configWithoutRoms), len(romsWithoutConfig))
+	log += fmt.Sprintf("Run ID: %s\n\n", result.RunID)
+	log += fmt.Sprintf("Found %d config files in: %s\n", result.ConfigCount, result.ConfigDirectory)
+	log += fmt.Sprintf("Found %d original and %d generated config files%s\n", result.ConfigCount-result.GeneratedConfigs, result.GeneratedConfigs, p.generatedSourcesNote())
+	log += fmt.Sprintf("Found %d roms in: %s\n", result.RomCount, result.RomDirectory)
+	log += fmt.Sprintf("Ignored %d files in: %s\n", result.IgnoredFiles, result.RomDirectory)
+	log += fmt.Sprintf("Link mode: %s\n\n", p.mode())
+	log += fmt.Sprintf("Missing ROMs: %d\nMissing config: %d\n", result.MissingRoms, result.MissingConfigs)
+	if p.validateOverlays {
+		log += fmt.Sprintf("Broken config: %d\n", result.BrokenConfigs)
+	}
+	log += "\n"
+
+	log += fmt.Sprintf("Created %d new files\n", result.CreatedFiles)
+	log += fmt.Sprintf("Skipped %d new files\n", result.SkippedFiles)
+	log += fmt.Sprintf("Failed %d new files\n\n", result.FailedFiles)
+
+	if len(brokenConfigLines) > 0 {
+		sortAlphabetical(brokenConfigLines)
+		log += fmt.Sprintf("BROKEN CONFIG\n%s\n\n", strings.Join(brokenConfigLines, "\n"))
+	}
+
+	if len(result.UnmatchedConfigs) > 0 {
+		log += fmt.Sprintf("CONFIG WITH MISSING ROMS\n%s\n\n", strings.Join(result.UnmatchedConfigs, "\n"))
+	}
+
+	if len(result.UnmatchedRoms) > 0 {
+		log += fmt.Sprintf("ROMS WITH MISSING CONFIG\n%s\n\n", strings.Join(result.UnmatchedRoms, "\n"))
+	}
+
+	if len(ignoredFileLines) > 0 {
+		sortAlphabetical(ignoredFileLines)
+		log += fmt.Sprintf("IGNORED FILES\n%s\n\n", strings.Join(ignoredFileLines, "\n"))
+	}
+
+	if len(result.Failed) > 0 {
+		failedLines := []string{}
+		for _, entry := range result.Failed {
+			failedLines = append(failedLines, fmt.Sprintf("%s -> %s (%s)", entry.RomFile, entry.NewFile, entry.Reason))
+		}
+		sortAlphabetical(failedLines)
+		log += fmt.Sprintf("FAILED FILES\n%s\n\n", strings.Join(failedLines, "\n"))
+	}
+
+	if len(result.Rejected) > 0 {
+		rejectedLines := []string{}
+		for _, entry := range result.Rejected {
+			rejectedLines = append(rejectedLines, newFileLine(entry))
+		}
+		sortAlphabetical(rejectedLines)
+		log += fmt.Sprintf("REJECTED DURING REVIEW\n%s\n\n", strings.Join(rejectedLines, "\n"))
+	}
 
-	totalFileCount := len(createdFiles[MatchTypeExact]) + len(createdFiles[MatchTypeAlternate]) + len(createdFiles[MatchTypeFuzzy])
-	skippedFileCount := 0
-	if !shouldInclude(MatchTypeExact, matchFlag) {
-		skippedFileCount += len(createdFiles[MatchTypeExact])
+	if len(otherSkippedLines) > 0 {
+		sortAlphabetical(otherSkippedLines)
+		log += fmt.Sprintf("SKIPPED FILES\n%s\n\n", strings.Join(otherSkippedLines, "\n"))
 	}
-	if !shouldInclude(MatchTypeAlternate, matchFlag) {
-		skippedFileCount += len(createdFiles[MatchTypeAlternate])
+
+	if len(newFiles[MatchTypeExact]) > 0 {
+		sortAlphabetical(newFiles[MatchTypeExact])
+		log += fmt.Sprintf("NEW FILES (EXACT MATCHES)%s\n%s\n\n", p.skipped(MatchTypeExact, matchFlag), strings.Join(newFiles[MatchTypeExact], "\n"))
 	}
-	if !shouldInclude(MatchTypeFuzzy, matchFlag) {
-		skippedFileCount += len(createdFiles[MatchTypeFuzzy])
+
+	if len(newFiles[MatchTypeDat]) > 0 {
+		sortAlphabetical(newFiles[MatchTypeDat])
+		log += fmt.Sprintf("NEW FILES (DAT MATCHES)%s\n%s\n\n", p.skipped(MatchTypeDat, matchFlag), strings.Join(newFiles[MatchTypeDat], "\n"))
 	}
-	createdFilesCount := totalFileCount - skippedFileCount
 
-	log += fmt.Sprintf("Created %d new files\n", createdFilesCount)
-	log += fmt.Sprintf("Skipped %d new files\n\n", skippedFileCount)
+	if len(newFiles[MatchTypeAlternate]) > 0 {
+		sortAlphabetical(newFiles[MatchTypeAlternate])
+		log += fmt.Sprintf("NEW FILES (GOOD MATCHES)%s\n%s\n\n", p.skipped(MatchTypeAlternate, matchFlag), strings.Join(newFiles[MatchTypeAlternate], "\n"))
+	}
 
-	if len(configWithoutRoms) > 0 {
-		sortAlphabetical(configWithoutRoms)
-		log += fmt.Sprintf("CONFIG WITH MISSING ROMS\n%s\n\n", strings.Join(configWithoutRoms, "\n"))
+	if len(newFiles[MatchTypeFuzzy]) > 0 {
+		sortAlphabetical(newFiles[MatchTypeFuzzy])
+		log += fmt.Sprintf("NEW FILES (FUZZY MATCHES)%s\n%s\n\n", p.skipped(MatchTypeFuzzy, matchFlag), strings.Join(newFiles[MatchTypeFuzzy], "\n"))
 	}
 
-	if len(romsWithoutConfig) > 0 {
-		sortAlphabetical(romsWithoutConfig)
-		log += fmt.Sprintf("ROMS WITH MISSING CONFIG\n%s\n\n", strings.Join(romsWithoutConfig, "\n"))
+	if len(newFiles[MatchTypeScored]) > 0 {
+		sortAlphabetical(newFiles[MatchTypeScored])
+		log += fmt.Sprintf("NEW FILES (SCORED MATCHES, MINIMUM SCORE %.2f)%s\n%s\n\n", p.scoreThreshold(), p.skipped(MatchTypeScored, matchFlag), strings.Join(newFiles[MatchTypeScored], "\n"))
 	}
 
-	if len(createdFiles[MatchTypeExact]) > 0 {
-		sortAlphabetical(createdFiles[MatchTypeExact])
-		log += fmt.Sprintf("NEW FILES (EXACT MATCHES)%s\n%s\n\n", skipped(MatchTypeExact, matchFlag), strings.Join(createdFiles[MatchTypeExact], "\n"))
+	// matches from custom matchers are listed in the order of the chain
+	for _, matcher := range p.matcherChain() {
+		t := matchType(matcher.Name())
+		if isBuiltInMatchType(t) || len(newFiles[t]) == 0 {
+			continue
+		}
+		sortAlphabetical(newFiles[t])
+		log += fmt.Sprintf("NEW FILES (%s MATCHES)%s\n%s\n\n", strings.ToUpper(matcher.Name()), p.skipped(t, matchFlag), strings.Join(newFiles[t], "\n"))
 	}
 
-	if len(createdFiles[MatchTypeAlternate]) > 0 {
-		sortAlphabetical(createdFiles[MatchTypeAlternate])
-		log += fmt.Sprintf("NEW FILES (GOOD MATCHES)%s\n%s\n\n", skipped(MatchTypeAlternate, matchFlag), strings.Join(createdFiles[MatchTypeAlternate], "\n"))
+	if len(newFiles[MatchTypeOverride]) > 0 {
+		sortAlphabetical(newFiles[MatchTypeOverride])
+		log += fmt.Sprintf("NEW FILES (OVERRIDES)\n%s\n\n", strings.Join(newFiles[MatchTypeOverride], "\n"))
 	}
 
-	if len(createdFiles[MatchTypeFuzzy]) > 0 {
-		sortAlphabetical(createdFiles[MatchTypeFuzzy])
-		log += fmt.Sprintf("NEW FILES (FUZZY MATCHES)%s\n%s\n\n", skipped(MatchTypeFuzzy, matchFlag), strings.Join(createdFiles[MatchTypeFuzzy], "\n"))
+	if len(newFiles[MatchTypeTemplate]) > 0 {
+		sortAlphabetical(newFiles[MatchTypeTemplate])
+		log += fmt.Sprintf("NEW FILES (FROM FALLBACK TEMPLATE)\n%s\n\n", strings.Join(newFiles[MatchTypeTemplate], "\n"))
 	}
 
 	// write the log to a file and swallow any errors
-	if err := p.writeLogToFile(configPath, log); err != nil {
-		fmt.Errorf("failed to write log file: %s", err.Error())
+	logPath, err := p.writeLogToFile(result.ConfigDirectory, logFileName(result.RunID), log)
+	if err != nil {
+		fmt.Printf("failed to write log file: %s\n", err.Error())
+	} else {
+		result.LogPath = logPath
+	}
+}
+
+// newFileLine describes a new file in the log i.e. "rom.n64 -> rom.cfg copied from: config.cfg"
+func newFileLine(entry ReportEntry) string {
+	line := fmt.Sprintf("%s -> %s %s: %s", entry.RomFile, entry.NewFile, entry.description(), entry.ConfigFile)
+	if entry.MatchType == MatchTypeScored {
+		line += fmt.Sprintf(" (score: %.2f)", entry.Score)
+	}
+	if entry.CanonicalName != "" {
+		line += fmt.Sprintf(" (DAT name: %s)", entry.CanonicalName)
+	}
+	if len(entry.Alternatives) > 0 {
+		line += fmt.Sprintf(" (alternatives considered: %s)", strings.Join(entry.Alternatives, ", "))
 	}
+	return line
 }
 
-// writeLogToFile write the log to a log file in the config directory
-func (p *Patcher) writeLogToFile(configDirPath, log string) error {
-	logName := fmt.Sprintf("patch-log.%d.log", time.Now().Unix())
+// writeLogToFile write the log to a log file in the config directory and returns its path
+func (p *Patcher) writeLogToFile(configDirPath, logName, log string) (string, error) {
 	logPath := filepath.Join(configDirPath, logName)
-	err := os.WriteFile(logPath, []byte(log), 0644)
-	if err == nil {
-		fmt.Printf("wrote log file to: %s\n", logPath)
+	if err := os.WriteFile(logPath, []byte(log), 0644); err != nil {
+		return "", err
 	}
-	return err
+	fmt.Printf("wrote log file to: %s\n", logPath)
+	return logPath, nil
+}
+
+// logFileName returns the file name used for the log of the given run
+func logFileName(runID string) string {
+	return fmt.Sprintf("patch-log.%s.log", runID)
 }
 
 // sortAlphabetical sorts a slice alphabetically
@@ -285,30 +671,26 @@ func sortAlphabetical(s []string) {
 	sort.Sort(sort.StringSlice(s))
 }
 
-func skipped(matchType matchType, matchFlag matchType) string {
-	if !shouldInclude(matchType, matchFlag) {
+// skipped marks the log section of a match type which is not included at the match level
+func (p *Patcher) skipped(matchType matchType, matchFlag matchType) string {
+	if !p.shouldInclude(matchType, matchFlag) {
 		return " [SKIPPED]"
 	}
 	return ""
 }
 
-// shouldInclude returns whether the current match type should be included in
-// processing based on the given match flag value
-func shouldInclude(matchType, matchFlag matchType) bool {
+// shouldInclude returns whether the current match type should be included in processing based on
+// the given match flag value. Matches are included if their matcher comes no later in the matcher
+// chain than the match level.
+func (p *Patcher) shouldInclude(matchType, matchFlag matchType) bool {
 	if matchType == MatchTypeNone {
 		return false
 	}
-	switch matchFlag {
-	case MatchTypeExact:
-		if matchType == MatchTypeExact {
-			return true
-		}
-	case MatchTypeAlternate:
-		if matchType == MatchTypeExact || matchType == MatchTypeAlternate {
-			return true
-		}
-	case MatchTypeFuzzy:
+	// templates are only used when asked for and overrides are decided by the user so they are
+	// always included
+	if matchType == MatchTypeTemplate || matchType == MatchTypeOverride {
 		return true
 	}
-	return false
+	rank, level := p.matchRank(matchType), p.matchRank(matchFlag)
+	return rank >= 0 && rank <= level
 }
@@ -0,0 +1,203 @@
+package patching
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrPlanOutdated is returned when a plan can no longer be applied because the config or ROM
+// directory has changed since the plan was made
+var ErrPlanOutdated = errors.New("plan is out of date")
+
+// Plan is the exact set of files a patch run creates. Plans are written by a dry run so they can
+// be reviewed, or edited, and then applied later without working the matches out again.
+type Plan struct {
+	RunID           string          `json:"run_id"`
+	CreatedAt       time.Time       `json:"created_at"`
+	ConfigDirectory string          `json:"config_directory"`
+	RomDirectory    string          `json:"rom_directory"`
+	Operations      []PlanOperation `json:"operations"`
+}
+
+// PlanOperation creates a single new config from its source config. Configs generated from the
+// fallback template have no source to copy so their contents are recorded instead.
+type PlanOperation struct {
+	File      string    `json:"file"`
+	Source    string    `json:"source"`
+	Rom       string    `json:"rom"`
+	MatchType matchType `json:"match_type"`
+	Score     float64   `json:"score,omitempty"`
+	LinkMode  linkMode  `json:"link_mode,omitempty"`
+	Contents  string    `json:"contents,omitempty"`
+}
+
+// PlanFileName returns the file name used for the plan of the given run
+func PlanFileName(runID string) string {
+	return fmt.Sprintf("patch-plan.%s.json", runID)
+}
+
+// LoadPlan reads a plan from the given file
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	return plan, nil
+}
+
+// Save writes the plan to the given file
+func (pl *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(pl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// planOperation builds the operation which creates the new config for the given match
+func (p *Patcher) planOperation(match *match) PlanOperation {
+	op := PlanOperation{
+		File:      match.rom.ConfigName(),
+		Source:    match.configFile.FileName,
+		Rom:       match.rom.Path,
+		MatchType: match.matchType,
+		Score:     match.score,
+	}
+	if match.matchType == MatchTypeTemplate {
+		op.Contents = string(match.rendered)
+	} else {
+		op.LinkMode = p.mode()
+	}
+	return op
+}
+
+// Apply creates exactly the files listed in the plan. Nothing is created if any of the plan's
+// preconditions no longer hold, i.e. a source config or ROM has been removed or one of the new
+// files already exists, as the plan would no longer do what was reviewed.
+func (p *Patcher) Apply(plan *Plan) error {
+	if err := plan.validate(); err != nil {
+		return err
+	}
+	if problems := p.checkPlan(plan); len(problems) > 0 {
+		return fmt.Errorf("%w:\n  %s", ErrPlanOutdated, strings.Join(problems, "\n  "))
+	}
+
+	runID := newRunID(plan.ConfigDirectory)
+	manifest := &Manifest{
+		RunID:           runID,
+		CreatedAt:       time.Now(),
+		ConfigDirectory: plan.ConfigDirectory,
+		RomDirectory:    plan.RomDirectory,
+		Files:           []ManifestEntry{},
+	}
+
+	createdFiles := []string{}
+	failedFiles := []string{}
+	for _, op := range plan.Operations {
+		// only do the file operations if --commit was specified
+		if !p.commit {
+			createdFiles = append(createdFiles, op.File)
+			continue
+		}
+		if p.failFast && len(failedFiles) > 0 {
+			failedFiles = append(failedFiles, fmt.Sprintf("%s (%s)", op.File, errFailFast))
+			continue
+		}
+		if err := p.createConfig(plan.ConfigDirectory, op); err != nil {
+			failedFiles = append(failedFiles, fmt.Sprintf("%s (%s)", op.File, err))
+			continue
+		}
+		manifest.Files = append(manifest.Files, p.manifestEntry(plan.ConfigDirectory, op))
+		createdFiles = append(createdFiles, op.File)
+	}
+
+	p.produceApplyLog(plan, runID, createdFiles, failedFiles)
+
+	// record exactly which files were created so the run can be rolled back later
+	if p.commit && len(manifest.Files) > 0 {
+		if err := manifest.save(); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+
+	if len(failedFiles) > 0 {
+		return fmt.Errorf("%w: %d failed", ErrFilesFailed, len(failedFiles))
+	}
+	return nil
+}
+
+// validate makes sure an edited plan only creates files in its config directory and never creates
+// the same file twice
+func (pl *Plan) validate() error {
+	if pl.ConfigDirectory == "" {
+		return errors.New("plan is missing a config_directory")
+	}
+	files := map[string]bool{}
+	for i, op := range pl.Operations {
+		if op.File == "" || (op.Source == "" && op.MatchType != MatchTypeTemplate) {
+			return fmt.Errorf("operation %d must have both a file and a source", i+1)
+		}
+		if filepath.Base(op.File) != op.File || (op.Source != "" && filepath.Base(op.Source) != op.Source) {
+			return fmt.Errorf("operation %d must only use files in the config directory", i+1)
+		}
+		if files[strings.ToLower(op.File)] {
+			return fmt.Errorf("operation %d creates %s more than once", i+1, op.File)
+		}
+		files[strings.ToLower(op.File)] = true
+	}
+	return nil
+}
+
+// checkPlan describes every operation in the plan whose preconditions no longer hold
+func (p *Patcher) checkPlan(plan *Plan) []string {
+	problems := []string{}
+	for _, op := range plan.Operations {
+		if op.MatchType != MatchTypeTemplate && !p.fileManager.FileExists(plan.ConfigDirectory, op.Source) {
+			problems = append(problems, fmt.Sprintf("%s: source %s no longer exists", op.File, op.Source))
+		}
+		if p.fileManager.FileExists(plan.ConfigDirectory, op.File) {
+			problems = append(problems, fmt.Sprintf("%s: already exists", op.File))
+		}
+		if op.Rom != "" && plan.RomDirectory != "" && !p.fileManager.FileExists(plan.RomDirectory, op.Rom) {
+			problems = append(problems, fmt.Sprintf("%s: ROM %s no longer exists", op.File, op.Rom))
+		}
+	}
+	return problems
+}
+
+// produceApplyLog writes a log file to the config directory describing what applying the plan did
+func (p *Patcher) produceApplyLog(plan *Plan, runID string, createdFiles, failedFiles []string) {
+	log := ""
+	if !p.commit {
+		log = "[DRY]\n\n"
+	}
+	log += fmt.Sprintf("Run ID: %s\n\n", runID)
+	log += fmt.Sprintf("Applied plan from run %s to: %s\n\n", plan.RunID, plan.ConfigDirectory)
+	log += fmt.Sprintf("Created %d new files\n", len(createdFiles))
+	log += fmt.Sprintf("Failed %d new files\n\n", len(failedFiles))
+
+	if len(failedFiles) > 0 {
+		sortAlphabetical(failedFiles)
+		log += fmt.Sprintf("FAILED FILES\n%s\n\n", strings.Join(failedFiles, "\n"))
+	}
+
+	if len(createdFiles) > 0 {
+		sortAlphabetical(createdFiles)
+		log += fmt.Sprintf("NEW FILES\n%s\n\n", strings.Join(createdFiles, "\n"))
+	}
+
+	// write the log to a file and swallow any errors
+	logName := fmt.Sprintf("apply-log.%s.%s.log", plan.RunID, runID)
+	if _, err := p.writeLogToFile(plan.ConfigDirectory, logName, log); err != nil {
+		fmt.Printf("failed to write log file: %s\n", err.Error())
+	}
+}
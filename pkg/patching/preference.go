@@ -0,0 +1,119 @@
+package patching
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultRegionPriority is the order regions are preferred in when several configs match the same ROM
+var DefaultRegionPriority = []string{"USA", "World", "Europe", "Japan"}
+
+// WithRegionPriority sets the order regions are preferred in when several configs match the same ROM.
+// Regions may be given as No-Intro names ("USA"), GoodTools codes ("U") or TOSEC codes ("US").
+func WithRegionPriority(regions ...string) Option {
+	return func(p *Patcher) {
+		p.regionPriority = normaliseRegions(regions)
+	}
+}
+
+// selectBestMatches reduces the matches to a single match per ROM. The losing matches are kept on
+// the winning match as alternatives so they can be reported.
+func (p *Patcher) selectBestMatches(matches []*match) []*match {
+	candidates := map[*Rom][]*match{}
+	for _, match := range matches {
+		candidates[match.rom] = append(candidates[match.rom], match)
+	}
+
+	selected := []*match{}
+	for _, match := range matches {
+		romCandidates, ok := candidates[match.rom]
+		if !ok {
+			// the best match for this ROM has already been selected
+			continue
+		}
+		delete(candidates, match.rom)
+
+		sort.SliceStable(romCandidates, func(i, j int) bool {
+			return p.isBetterMatch(romCandidates[i], romCandidates[j])
+		})
+		best := romCandidates[0]
+		best.alternatives = romCandidates[1:]
+		selected = append(selected, best)
+	}
+	return selected
+}
+
+// isBetterMatch returns true if match a should be used over match b. Match types from earlier in the
+// matcher chain always win, followed by more similar names, configs which already belong to the ROM,
+// configs from the same region as the ROM, configs from a higher priority region and finally the config
+// name so the result is always the same.
+func (p *Patcher) isBetterMatch(a, b *match) bool {
+	if aRank, bRank := p.matchRank(a.matchType), p.matchRank(b.matchType); aRank != bRank {
+		return aRank < bRank
+	}
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if a.isExisting != b.isExisting {
+		return a.isExisting
+	}
+	aSameRegion, bSameRegion := sharesRegion(a.configFile, a.rom), sharesRegion(b.configFile, b.rom)
+	if aSameRegion != bSameRegion {
+		return aSameRegion
+	}
+	if aRank, bRank := p.regionRank(a.configFile), p.regionRank(b.configFile); aRank != bRank {
+		return aRank < bRank
+	}
+	return strings.ToLower(a.configFile.FileName) < strings.ToLower(b.configFile.FileName)
+}
+
+// regionRank returns the position of the config's highest priority region. Configs without a
+// prioritised region are ranked after all of the prioritised regions.
+func (p *Patcher) regionRank(configFile *Rom) int {
+	priority := p.regionPriority
+	if priority == nil {
+		priority = DefaultRegionPriority
+	}
+	for i, region := range priority {
+		if containsItem(configFile.Regions, region) {
+			return i
+		}
+	}
+	return len(priority)
+}
+
+// sharesRegion returns true if the config and the ROM have at least one region in common
+func sharesRegion(configFile, rom *Rom) bool {
+	for _, region := range rom.Regions {
+		if containsItem(configFile.Regions, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// normaliseRegions converts any GoodTools or TOSEC region codes into No-Intro region names
+func normaliseRegions(regions []string) []string {
+	normalised := []string{}
+	for _, region := range regions {
+		region = strings.TrimSpace(region)
+		if region == "" {
+			continue
+		}
+		if names := parseRegions(region); len(names) > 0 {
+			normalised = append(normalised, names...)
+			continue
+		}
+		normalised = append(normalised, region)
+	}
+	return uniqueItems(normalised)
+}
+
+// alternativeNames returns the config names of the alternatives which were considered for a match
+func (m *match) alternativeNames() []string {
+	names := []string{}
+	for _, alternative := range m.alternatives {
+		names = append(names, alternative.configFile.FileName)
+	}
+	return names
+}
@@ -0,0 +1,65 @@
+package patching
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// WithGeneratedSources makes the patcher use configs it created in previous runs as match sources,
+// as well as the original Bezel Project configs. Generated configs are copies of an original so
+// using them as a source compounds fuzzy matches, which is why they are not used by default.
+func WithGeneratedSources() Option {
+	return func(p *Patcher) {
+		p.generatedSources = true
+	}
+}
+
+// generatedConfigs returns the configs in the config directory which were created by previous
+// patch runs, keyed by their lower case file name, along with the ID of the run which created them.
+// The manifests record every file the patcher creates so configs which have been rolled back or
+// pruned are not counted.
+func generatedConfigs(configDirPath string) (map[string]string, error) {
+	generated := map[string]string{}
+	runIDs, err := ListManifests(configDirPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return generated, nil
+		}
+		return nil, err
+	}
+
+	for _, runID := range runIDs {
+		manifest, err := LoadManifest(configDirPath, runID)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range manifest.Files {
+			generated[strings.ToLower(entry.File)] = runID
+		}
+	}
+	return generated, nil
+}
+
+// splitGeneratedConfigs separates the original configs from the configs which were generated by
+// previous patch runs. Generated configs are mapped to the ID of the run which created them.
+func splitGeneratedConfigs(configFiles []*Rom, generated map[string]string) ([]*Rom, map[*Rom]string) {
+	originals := []*Rom{}
+	generatedFiles := map[*Rom]string{}
+	for _, configFile := range configFiles {
+		if runID, ok := generated[strings.ToLower(configFile.FileName)]; ok {
+			generatedFiles[configFile] = runID
+			continue
+		}
+		originals = append(originals, configFile)
+	}
+	return originals, generatedFiles
+}
+
+// generatedSourcesNote explains in the log whether generated configs were used as match sources
+func (p *Patcher) generatedSourcesNote() string {
+	if p.generatedSources {
+		return " (generated configs are used as match sources)"
+	}
+	return " (generated configs are not used as match sources)"
+}
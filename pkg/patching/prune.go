@@ -0,0 +1,137 @@
+package patching
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// QuarantineDirectory is the directory inside the config directory which pruned configs are moved to
+const QuarantineDirectory = "quarantine"
+
+// ErrMoveFailed is returned when one or more configs could not be moved to the quarantine directory
+var ErrMoveFailed = errors.New("failed to quarantine config files")
+
+// Prune moves every config created by a previous patch run whose ROM no longer exists into the
+// quarantine directory, under a subdirectory for the run which created it so configs with the same
+// name from different runs never overwrite each other. Only files recorded in a manifest are ever
+// moved so the original Bezel Project configs are never touched, and files whose contents have
+// changed since they were created are kept. If any files could not be moved, they are kept in
+// their manifest so the prune can be retried and ErrMoveFailed is returned.
+func (p *Patcher) Prune(configDirPath string) error {
+	runIDs, err := ListManifests(configDirPath)
+	if err != nil {
+		return err
+	}
+	if len(runIDs) == 0 {
+		return ErrNoManifest
+	}
+
+	quarantinePath := filepath.Join(configDirPath, QuarantineDirectory)
+	prunedFiles := []string{}
+	modifiedFiles := []string{}
+	failedFiles := []string{}
+	skippedRuns := []string{}
+	for _, runID := range runIDs {
+		manifest, err := LoadManifest(configDirPath, runID)
+		if err != nil {
+			return err
+		}
+		// the manifest may have been copied from elsewhere so always trust the given directory
+		manifest.ConfigDirectory = configDirPath
+
+		// a missing ROM directory is more likely to be an unmounted drive than deleted ROMs
+		if _, err := p.fileManager.GetDirectoryContents(manifest.RomDirectory); err != nil {
+			skippedRuns = append(skippedRuns, fmt.Sprintf("%s (ROM directory not found: %s)", runID, manifest.RomDirectory))
+			continue
+		}
+
+		remaining := []ManifestEntry{}
+		for _, entry := range manifest.Files {
+			dangling := p.isDanglingLink(configDirPath, entry)
+			if !dangling && !p.fileManager.FileExists(configDirPath, entry.File) {
+				continue
+			}
+			if p.fileManager.FileExists(manifest.RomDirectory, entry.Rom) {
+				remaining = append(remaining, entry)
+				continue
+			}
+
+			// the user may have edited the config since it was created so it is no longer ours to move.
+			// a link to a deleted source has nothing to compare but the link itself is unchanged
+			if !dangling {
+				contents, err := p.fileManager.ReadFile(configDirPath, entry.File)
+				if err != nil || (checksum(contents) != entry.Checksum && !p.isStillLinked(configDirPath, entry, contents)) {
+					modifiedFiles = append(modifiedFiles, fmt.Sprintf("%s (ROM not found: %s)", entry.File, entry.Rom))
+					remaining = append(remaining, entry)
+					continue
+				}
+			}
+
+			// only do the file operations if --commit was specified
+			if p.commit {
+				if err := p.fileManager.MoveFile(configDirPath, entry.File, filepath.Join(quarantinePath, runID)); err != nil {
+					failedFiles = append(failedFiles, fmt.Sprintf("%s (%s)", entry.File, err.Error()))
+					remaining = append(remaining, entry)
+					continue
+				}
+			}
+			prunedFiles = append(prunedFiles, fmt.Sprintf("%s (ROM not found: %s)", filepath.Join(runID, entry.File), entry.Rom))
+		}
+
+		if p.commit {
+			manifest.Files = remaining
+			if err := manifest.save(); err != nil {
+				return fmt.Errorf("failed to update manifest: %w", err)
+			}
+		}
+	}
+
+	p.producePruneLog(configDirPath, quarantinePath, prunedFiles, modifiedFiles, failedFiles, skippedRuns)
+
+	if len(failedFiles) > 0 {
+		return fmt.Errorf("%w: %d failed", ErrMoveFailed, len(failedFiles))
+	}
+	return nil
+}
+
+// producePruneLog writes a log file to the config directory describing what the prune did
+func (p *Patcher) producePruneLog(configPath, quarantinePath string, prunedFiles, modifiedFiles, failedFiles, skippedRuns []string) {
+	log := ""
+	if !p.commit {
+		log = "[DRY]\n\n"
+	}
+	log += fmt.Sprintf("Pruned configs in: %s\n\n", configPath)
+	log += fmt.Sprintf("Moved %d files to: %s\n", len(prunedFiles), quarantinePath)
+	log += fmt.Sprintf("Kept %d modified files\n", len(modifiedFiles))
+	log += fmt.Sprintf("Failed to move %d files\n", len(failedFiles))
+	log += fmt.Sprintf("Skipped %d runs\n\n", len(skippedRuns))
+
+	if len(failedFiles) > 0 {
+		sortAlphabetical(failedFiles)
+		log += fmt.Sprintf("FAILED FILES (NOT MOVED)\n%s\n\n", strings.Join(failedFiles, "\n"))
+	}
+
+	if len(skippedRuns) > 0 {
+		sortAlphabetical(skippedRuns)
+		log += fmt.Sprintf("SKIPPED RUNS\n%s\n\n", strings.Join(skippedRuns, "\n"))
+	}
+
+	if len(modifiedFiles) > 0 {
+		sortAlphabetical(modifiedFiles)
+		log += fmt.Sprintf("MODIFIED FILES (NOT MOVED)\n%s\n\n", strings.Join(modifiedFiles, "\n"))
+	}
+
+	if len(prunedFiles) > 0 {
+		sortAlphabetical(prunedFiles)
+		log += fmt.Sprintf("PRUNED FILES\n%s\n\n", strings.Join(prunedFiles, "\n"))
+	}
+
+	// write the log to a file and swallow any errors
+	logName := fmt.Sprintf("prune-log.%d.log", time.Now().Unix())
+	if _, err := p.writeLogToFile(configPath, logName, log); err != nil {
+		fmt.Printf("failed to write log file: %s\n", err.Error())
+	}
+}
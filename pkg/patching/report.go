@@ -0,0 +1,273 @@
+package patching
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reportStatus is used to identify what happened to a single entry in a report
+type reportStatus string
+
+const (
+	// ReportStatusCreated means a new config was created for the ROM
+	ReportStatusCreated reportStatus = "created"
+	// ReportStatusSkipped means a config matched the ROM but the match type was not included
+	ReportStatusSkipped reportStatus = "skipped"
+	// ReportStatusExisting means the ROM already had a config
+	ReportStatusExisting reportStatus = "existing"
+	// ReportStatusFailed means a new config could not be created for the ROM
+	ReportStatusFailed reportStatus = "failed"
+	// ReportStatusAlternative means a config matched the ROM but another config was a better match or
+	// was picked in the review
+	ReportStatusAlternative reportStatus = "alternative"
+	// ReportStatusUnmatched means a ROM had no matching config or a config had no matching ROM
+	ReportStatusUnmatched reportStatus = "unmatched"
+	// ReportStatusBroken means a config was not used because its overlay chain is broken
+	ReportStatusBroken reportStatus = "broken"
+	// ReportStatusIgnored means a file in the ROM directory is not a ROM
+	ReportStatusIgnored reportStatus = "ignored"
+	// ReportStatusRejected means the match was rejected during review
+	ReportStatusRejected reportStatus = "rejected"
+	// ReportStatusGenerated means a config was not used as a source because a previous run created it
+	ReportStatusGenerated reportStatus = "generated"
+)
+
+// ReportFormat is used to identify the format a report is written in
+type ReportFormat string
+
+const (
+	// ReportFormatText is the plain text log which is always written to the config directory
+	ReportFormatText ReportFormat = "text"
+	// ReportFormatJSON writes the report as JSON
+	ReportFormatJSON ReportFormat = "json"
+	// ReportFormatCSV writes the report as CSV with one row per entry
+	ReportFormatCSV ReportFormat = "csv"
+)
+
+// Report is a structured record of every match considered by a patch run
+type Report struct {
+	RunID           string        `json:"run_id"`
+	ConfigDirectory string        `json:"config_directory"`
+	RomDirectory    string        `json:"rom_directory"`
+	DryRun          bool          `json:"dry_run"`
+	Entries         []ReportEntry `json:"entries"`
+}
+
+// ReportEntry records a single config file and ROM pairing and what happened to it. Either the
+// config file or the ROM file is empty for unmatched, broken and ignored entries.
+type ReportEntry struct {
+	ConfigFile string    `json:"config_file"`
+	RomFile    string    `json:"rom_file"`
+	NewFile    string    `json:"new_file,omitempty"`
+	MatchType  matchType `json:"match_type"`
+	Score      float64   `json:"score,omitempty"`
+	// CanonicalName is the ROM's name in the DAT when it was resolved through the DAT
+	CanonicalName string       `json:"canonical_name,omitempty"`
+	LinkMode      linkMode     `json:"link_mode,omitempty"`
+	Status        reportStatus `json:"status"`
+	Reason        string       `json:"reason,omitempty"`
+	// Alternatives are the other configs which matched the ROM but were not as good a match
+	Alternatives []string `json:"alternatives,omitempty"`
+}
+
+// ParseReportFormat returns the report format with the given name
+func ParseReportFormat(name string) (ReportFormat, error) {
+	for _, f := range []ReportFormat{ReportFormatText, ReportFormatJSON, ReportFormatCSV} {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+	return ReportFormatText, fmt.Errorf("unknown report format: %s", name)
+}
+
+// ReportFileName returns the file name used for the report of the given run in the given format
+func ReportFileName(runID string, format ReportFormat) string {
+	return fmt.Sprintf("patch-report.%s.%s", runID, format)
+}
+
+// Write writes the report to the writer in the given format. Text reports are not supported as
+// the text log is always written by the patcher.
+func (r *Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case ReportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"config_file", "rom_file", "new_file", "match_type", "score", "status", "reason"}); err != nil {
+			return err
+		}
+		for _, entry := range r.Entries {
+			score := ""
+			if entry.Score > 0 {
+				score = strconv.FormatFloat(entry.Score, 'f', 2, 64)
+			}
+			if err := writer.Write([]string{entry.ConfigFile, entry.RomFile, entry.NewFile, string(entry.MatchType), score, string(entry.Status), entry.Reason}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+// produceReport builds the report for a patch run from its matches
+func (p *Patcher) produceReport(runID, romDirPath, configPath string, matches []*match, brokenConfigs, generatedConfigs map[*Rom]string, ignoredFiles map[string]string, matchFlag matchType) *Report {
+	report := &Report{
+		RunID:           runID,
+		ConfigDirectory: configPath,
+		RomDirectory:    romDirPath,
+		DryRun:          !p.commit,
+		Entries:         []ReportEntry{},
+	}
+
+	for _, match := range matches {
+		if match.matchType == MatchTypeNone {
+			if match.configFile != nil {
+				report.Entries = append(report.Entries, ReportEntry{
+					ConfigFile: match.configFile.FileName,
+					MatchType:  MatchTypeNone,
+					Status:     ReportStatusUnmatched,
+					Reason:     "no matching ROM",
+				})
+			} else {
+				reason := "no matching config"
+				if match.err != nil {
+					reason = match.err.Error()
+				}
+				report.Entries = append(report.Entries, ReportEntry{
+					RomFile:   match.rom.Path,
+					MatchType: MatchTypeNone,
+					Status:    ReportStatusUnmatched,
+					Reason:    reason,
+				})
+			}
+			continue
+		}
+
+		entry := ReportEntry{
+			ConfigFile:    match.configFile.FileName,
+			RomFile:       match.rom.Path,
+			NewFile:       match.rom.ConfigName(),
+			MatchType:     match.matchType,
+			Score:         match.score,
+			CanonicalName: match.canonicalName,
+			Alternatives:  match.alternativeNames(),
+		}
+		if match.matchType != MatchTypeTemplate {
+			entry.LinkMode = p.mode()
+		}
+		switch {
+		case match.isExisting:
+			entry.Status = ReportStatusExisting
+			entry.NewFile = ""
+			entry.Reason = fmt.Sprintf("%s already exists", match.rom.ConfigName())
+		case !p.shouldInclude(match.matchType, matchFlag):
+			entry.Status = ReportStatusSkipped
+			entry.Reason = fmt.Sprintf("%s matches are not included at the %s match level", match.matchType, matchFlag)
+		case match.decision == DecisionReject:
+			entry.Status = ReportStatusRejected
+			entry.Reason = "rejected during review"
+		case match.decision == DecisionSkip:
+			entry.Status = ReportStatusSkipped
+			entry.Reason = "skipped during review"
+			if match.err != nil {
+				entry.Reason = match.err.Error()
+			}
+		case errors.Is(match.err, errFailFast):
+			entry.Status = ReportStatusSkipped
+			entry.Reason = match.err.Error()
+		case match.err != nil:
+			entry.Status = ReportStatusFailed
+			entry.Reason = match.err.Error()
+		default:
+			entry.Status = ReportStatusCreated
+			entry.Reason = entry.description() + " " + match.configFile.FileName
+			if !p.commit {
+				entry.Reason += " (dry run)"
+			}
+		}
+		report.Entries = append(report.Entries, entry)
+
+		reason := "%s was a better match"
+		if match.decision == DecisionAccept {
+			reason = "%s was picked in the review"
+		}
+		for _, alternative := range match.alternatives {
+			report.Entries = append(report.Entries, ReportEntry{
+				ConfigFile: alternative.configFile.FileName,
+				RomFile:    alternative.rom.Path,
+				MatchType:  alternative.matchType,
+				Score:      alternative.score,
+				Status:     ReportStatusAlternative,
+				Reason:     fmt.Sprintf(reason, match.configFile.FileName),
+			})
+		}
+	}
+
+	// maps have no order so sort the broken, generated and ignored files to keep the report stable
+	brokenConfigFiles := []*Rom{}
+	for configFile := range brokenConfigs {
+		brokenConfigFiles = append(brokenConfigFiles, configFile)
+	}
+	sort.Slice(brokenConfigFiles, func(i, j int) bool {
+		return brokenConfigFiles[i].FileName < brokenConfigFiles[j].FileName
+	})
+	for _, configFile := range brokenConfigFiles {
+		report.Entries = append(report.Entries, ReportEntry{
+			ConfigFile: configFile.FileName,
+			MatchType:  MatchTypeNone,
+			Status:     ReportStatusBroken,
+			Reason:     brokenConfigs[configFile],
+		})
+	}
+
+	generatedConfigFiles := []*Rom{}
+	for configFile := range generatedConfigs {
+		generatedConfigFiles = append(generatedConfigFiles, configFile)
+	}
+	sort.Slice(generatedConfigFiles, func(i, j int) bool {
+		return generatedConfigFiles[i].FileName < generatedConfigFiles[j].FileName
+	})
+	for _, configFile := range generatedConfigFiles {
+		report.Entries = append(report.Entries, ReportEntry{
+			ConfigFile: configFile.FileName,
+			MatchType:  MatchTypeNone,
+			Status:     ReportStatusGenerated,
+			Reason:     fmt.Sprintf("generated by run %s", generatedConfigs[configFile]),
+		})
+	}
+
+	ignoredPaths := []string{}
+	for path := range ignoredFiles {
+		ignoredPaths = append(ignoredPaths, path)
+	}
+	sortAlphabetical(ignoredPaths)
+	for _, path := range ignoredPaths {
+		report.Entries = append(report.Entries, ReportEntry{
+			RomFile:   path,
+			MatchType: MatchTypeNone,
+			Status:    ReportStatusIgnored,
+			Reason:    ignoredFiles[path],
+		})
+	}
+
+	return report
+}
+
+// description describes how the new file was produced from its source i.e. "copied from"
+func (e ReportEntry) description() string {
+	if e.MatchType == MatchTypeTemplate {
+		return "generated from"
+	}
+	return e.LinkMode.description()
+}
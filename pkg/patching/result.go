@@ -0,0 +1,40 @@
+package patching
+
+// PatchResult summarises what a call to PatchDirectory did
+type PatchResult struct {
+	RunID           string
+	ConfigDirectory string
+	RomDirectory    string
+	LogPath         string
+	ConfigCount     int
+	RomCount        int
+	MissingRoms     int
+	MissingConfigs  int
+	BrokenConfigs   int
+	IgnoredFiles    int
+	CreatedFiles    int
+	SkippedFiles    int
+	FailedFiles     int
+	// GeneratedConfigs is how many of the configs were created by previous patch runs
+	GeneratedConfigs int
+
+	// Created lists the new configs, or the configs which would be created by a dry run
+	Created []ReportEntry
+	// Skipped lists the new configs which were not created because of the match level
+	Skipped []ReportEntry
+	// Existing lists the ROMs which already had a config
+	Existing []ReportEntry
+	// Failed lists the new configs which could not be created
+	Failed []ReportEntry
+	// Rejected lists the new configs which were rejected during review
+	Rejected []ReportEntry
+	// UnmatchedRoms lists the ROMs which have no matching config
+	UnmatchedRoms []string
+	// UnmatchedConfigs lists the configs which have no matching ROM
+	UnmatchedConfigs []string
+
+	// Report records every match considered by the run
+	Report *Report
+	// Plan lists the files the run created, or would have created for a dry run
+	Plan *Plan
+}
@@ -0,0 +1,232 @@
+package patching
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// decisionsFileName is the file in the config directory which review decisions are saved to
+const decisionsFileName = "patch-decisions.json"
+
+// decisionAction is used to identify what the user decided to do with a reviewed match
+type decisionAction string
+
+const (
+	// DecisionAccept creates the config from the chosen candidate
+	DecisionAccept decisionAction = "accept"
+	// DecisionReject never creates a config for the ROM from any of the candidates
+	DecisionReject decisionAction = "reject"
+	// DecisionSkip does nothing this run, the match will be reviewed again next time
+	DecisionSkip decisionAction = "skip"
+)
+
+// Decision is the outcome of reviewing a match. Config is the candidate the decision was made
+// about, which is not always the best candidate if the user picked a different one.
+type Decision struct {
+	Action decisionAction `json:"action"`
+	Config string         `json:"config"`
+}
+
+// ReviewItem describes a ROM whose match needs reviewing
+type ReviewItem struct {
+	Rom     string
+	NewFile string
+	// Candidates are the configs which matched the ROM, best first
+	Candidates []ReviewCandidate
+	// Index and Total give the position of the item in the review i.e. 3 of 10
+	Index int
+	Total int
+}
+
+// ReviewCandidate is a single config which matched the ROM under review
+type ReviewCandidate struct {
+	ConfigFile string
+	MatchType  matchType
+	Score      float64
+}
+
+// Reviewer decides what to do with matches which are not certain
+type Reviewer interface {
+	Review(item ReviewItem) (Decision, error)
+}
+
+// WithReviewer makes the patcher ask the reviewer about every alternate, fuzzy or scored match and
+// every ROM which matched several configs. Only accepted matches are created. Decisions are saved
+// to the config directory, so they are reused by later runs, but only when the run is committed.
+func WithReviewer(reviewer Reviewer) Option {
+	return func(p *Patcher) {
+		p.reviewer = reviewer
+	}
+}
+
+// reviewMatches asks the reviewer about each match which needs reviewing and returns every decision,
+// including the previous decisions. Previous decisions are reused so the same match is never asked
+// about twice. If the reviewer fails, the review stops and the run carries on with the matches which
+// have already been decided.
+func (p *Patcher) reviewMatches(configDirPath string, matches []*match, matchFlag matchType) (map[string]Decision, error) {
+	decisions, err := loadDecisions(configDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*match{}
+	for _, match := range matches {
+		if !p.needsReview(match, matchFlag) || p.fileManager.FileExists(configDirPath, match.rom.ConfigName()) {
+			continue
+		}
+		// reuse the previous decision as long as the config it was about can still be used
+		if decision, ok := decisions[match.rom.Path]; ok && findCandidate(p.candidates(match, matchFlag), decision.Config) != nil {
+			match.apply(decision)
+			continue
+		}
+		pending = append(pending, match)
+	}
+
+	var reviewErr error
+	for i, match := range pending {
+		// once the review has stopped, i.e. the user closed the input, nothing else is asked and
+		// anything not reviewed is left for the next run
+		if reviewErr != nil {
+			match.skipUnreviewed(reviewErr)
+			continue
+		}
+
+		candidates := p.candidates(match, matchFlag)
+		decision, err := p.reviewer.Review(match.reviewItem(candidates, i+1, len(pending)))
+		if err != nil {
+			reviewErr = err
+			match.skipUnreviewed(reviewErr)
+			continue
+		}
+		if findCandidate(candidates, decision.Config) == nil {
+			decision.Config = match.configFile.FileName
+		}
+		if decision.Action != DecisionSkip {
+			decisions[match.rom.Path] = decision
+		}
+		match.apply(decision)
+	}
+	return decisions, nil
+}
+
+// saveReviewDecisions saves the decisions once the accepted matches have been created. Decisions are
+// only remembered when the files they decided on are created, so a dry run saves nothing and an
+// accepted match whose file could not be created is reviewed again next time.
+func (p *Patcher) saveReviewDecisions(configDirPath string, decisions map[string]Decision, matches []*match) error {
+	if !p.commit {
+		return nil
+	}
+	for _, match := range matches {
+		if match.decision == DecisionAccept && !p.fileManager.FileExists(configDirPath, match.rom.ConfigName()) {
+			delete(decisions, match.rom.Path)
+		}
+	}
+	return saveDecisions(configDirPath, decisions)
+}
+
+// skipUnreviewed skips a match which was never reviewed because the review stopped early
+func (m *match) skipUnreviewed(reviewErr error) {
+	m.decision = DecisionSkip
+	m.err = fmt.Errorf("not reviewed, the review stopped early: %w", reviewErr)
+}
+
+// needsReview returns true if the match is not certain enough to be created without asking
+func (p *Patcher) needsReview(match *match, matchFlag matchType) bool {
+	if match.matchType == MatchTypeNone || match.matchType == MatchTypeTemplate || match.matchType == MatchTypeOverride || match.isExisting {
+		return false
+	}
+	if !p.shouldInclude(match.matchType, matchFlag) {
+		return false
+	}
+	return (match.matchType != MatchTypeExact && match.matchType != MatchTypeDat) || len(p.candidates(match, matchFlag)) > 1
+}
+
+// candidates returns the match and any alternatives which may be created at the match level. Other
+// alternatives are never offered as they would be skipped even if they were picked.
+func (p *Patcher) candidates(m *match, matchFlag matchType) []*match {
+	candidates := []*match{m}
+	for _, alternative := range m.alternatives {
+		if p.shouldInclude(alternative.matchType, matchFlag) {
+			candidates = append(candidates, alternative)
+		}
+	}
+	return candidates
+}
+
+// reviewItem describes the match and its candidates for the reviewer
+func (m *match) reviewItem(candidates []*match, index, total int) ReviewItem {
+	item := ReviewItem{
+		Rom:     m.rom.Path,
+		NewFile: m.rom.ConfigName(),
+		Index:   index,
+		Total:   total,
+	}
+	for _, candidate := range candidates {
+		item.Candidates = append(item.Candidates, ReviewCandidate{
+			ConfigFile: candidate.configFile.FileName,
+			MatchType:  candidate.matchType,
+			Score:      candidate.score,
+		})
+	}
+	return item
+}
+
+// findCandidate returns the candidate for the given config, or nil if the config is not a candidate
+func findCandidate(candidates []*match, configFile string) *match {
+	for _, candidate := range candidates {
+		if candidate.configFile.FileName == configFile {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// apply updates the match with the decision. Picking a different candidate swaps it with the best
+// candidate so the picked config is the one which gets copied.
+func (m *match) apply(decision Decision) {
+	m.decision = decision.Action
+	if decision.Action != DecisionAccept || decision.Config == m.configFile.FileName {
+		return
+	}
+
+	picked := findCandidate(append([]*match{m}, m.alternatives...), decision.Config)
+	alternatives := []*match{{configFile: m.configFile, rom: m.rom, matchType: m.matchType, score: m.score}}
+	for _, alternative := range m.alternatives {
+		if alternative != picked {
+			alternatives = append(alternatives, alternative)
+		}
+	}
+	m.configFile, m.matchType, m.score = picked.configFile, picked.matchType, picked.score
+	m.alternatives = alternatives
+}
+
+// loadDecisions loads the previous review decisions from the config directory
+func loadDecisions(configDirPath string) (map[string]Decision, error) {
+	decisions := map[string]Decision{}
+	data, err := os.ReadFile(filepath.Join(configDirPath, decisionsFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return decisions, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &decisions); err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// saveDecisions writes the review decisions to the config directory
+func saveDecisions(configDirPath string, decisions map[string]Decision) error {
+	if len(decisions) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(decisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configDirPath, decisionsFileName), data, 0644)
+}
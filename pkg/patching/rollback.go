@@ -0,0 +1,125 @@
+package patching
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrRemoveFailed is returned when one or more configs could not be removed by a rollback
+var ErrRemoveFailed = errors.New("failed to remove config files")
+
+// Rollback removes the files created by the given patch run from the config directory. If
+// no run ID is given, the most recent run is rolled back. Files whose contents have changed
+// since they were created are never removed. If any files could not be removed, they are kept
+// in the manifest so the rollback can be retried and ErrRemoveFailed is returned.
+func (p *Patcher) Rollback(configDirPath, runID string) error {
+	manifest, err := LoadManifest(configDirPath, runID)
+	if err != nil {
+		return err
+	}
+	// the manifest may have been copied from elsewhere so always trust the given directory
+	manifest.ConfigDirectory = configDirPath
+
+	removedFiles := []string{}
+	missingFiles := []string{}
+	modifiedFiles := []string{}
+	failedFiles := []string{}
+	remaining := []ManifestEntry{}
+	for _, entry := range manifest.Files {
+		dangling := p.isDanglingLink(configDirPath, entry)
+		if !dangling && !p.fileManager.FileExists(configDirPath, entry.File) {
+			missingFiles = append(missingFiles, entry.File)
+			continue
+		}
+
+		// a link to a deleted source has nothing to compare but the link itself is unchanged
+		if !dangling {
+			contents, err := p.fileManager.ReadFile(configDirPath, entry.File)
+			if err != nil || (checksum(contents) != entry.Checksum && !p.isStillLinked(configDirPath, entry, contents)) {
+				modifiedFiles = append(modifiedFiles, entry.File)
+				remaining = append(remaining, entry)
+				continue
+			}
+		}
+
+		// only do the file operations if --commit was specified
+		if p.commit {
+			if err := p.fileManager.RemoveFile(configDirPath, entry.File); err != nil {
+				failedFiles = append(failedFiles, fmt.Sprintf("%s (%s)", entry.File, err.Error()))
+				remaining = append(remaining, entry)
+				continue
+			}
+		}
+		removedFiles = append(removedFiles, entry.File)
+	}
+
+	if p.commit {
+		manifest.Files = remaining
+		if err := manifest.save(); err != nil {
+			return fmt.Errorf("failed to update manifest: %w", err)
+		}
+	}
+
+	p.produceRollbackLog(manifest.RunID, configDirPath, removedFiles, missingFiles, modifiedFiles, failedFiles)
+
+	if len(failedFiles) > 0 {
+		return fmt.Errorf("%w: %d failed", ErrRemoveFailed, len(failedFiles))
+	}
+	return nil
+}
+
+// produceRollbackLog writes a log file to the config directory describing what the rollback did
+func (p *Patcher) produceRollbackLog(runID, configPath string, removedFiles, missingFiles, modifiedFiles, failedFiles []string) {
+	log := ""
+	if !p.commit {
+		log = "[DRY]\n\n"
+	}
+	log += fmt.Sprintf("Rolled back run %s in: %s\n\n", runID, configPath)
+	log += fmt.Sprintf("Removed %d files\n", len(removedFiles))
+	log += fmt.Sprintf("Kept %d modified files\n", len(modifiedFiles))
+	log += fmt.Sprintf("Failed to remove %d files\n\n", len(failedFiles))
+
+	if len(failedFiles) > 0 {
+		sortAlphabetical(failedFiles)
+		log += fmt.Sprintf("FAILED FILES (NOT REMOVED)\n%s\n\n", strings.Join(failedFiles, "\n"))
+	}
+
+	if len(modifiedFiles) > 0 {
+		sortAlphabetical(modifiedFiles)
+		log += fmt.Sprintf("MODIFIED FILES (NOT REMOVED)\n%s\n\n", strings.Join(modifiedFiles, "\n"))
+	}
+
+	if len(missingFiles) > 0 {
+		sortAlphabetical(missingFiles)
+		log += fmt.Sprintf("ALREADY REMOVED\n%s\n\n", strings.Join(missingFiles, "\n"))
+	}
+
+	if len(removedFiles) > 0 {
+		sortAlphabetical(removedFiles)
+		log += fmt.Sprintf("REMOVED FILES\n%s\n\n", strings.Join(removedFiles, "\n"))
+	}
+
+	// write the log to a file and swallow any errors
+	logName := fmt.Sprintf("rollback-log.%s.%d.log", runID, time.Now().Unix())
+	if _, err := p.writeLogToFile(configPath, logName, log); err != nil {
+		fmt.Printf("failed to write log file: %s\n", err.Error())
+	}
+}
+
+// isStillLinked returns true if a linked file has the same contents as its source. Linked files
+// follow their source so a change to the source is not a change to the file.
+func (p *Patcher) isStillLinked(configDirPath string, entry ManifestEntry, contents []byte) bool {
+	if entry.LinkMode != LinkModeSymlink && entry.LinkMode != LinkModeHardlink {
+		return false
+	}
+	sourceContents, err := p.fileManager.ReadFile(configDirPath, entry.Source)
+	return err == nil && checksum(sourceContents) == checksum(contents)
+}
+
+// isDanglingLink returns true if a symlinked file is still in the config directory but its source
+// is not. Following the link would make the file look like it had already been removed.
+func (p *Patcher) isDanglingLink(configDirPath string, entry ManifestEntry) bool {
+	return entry.LinkMode == LinkModeSymlink && !p.fileManager.FileExists(configDirPath, entry.File) && p.fileManager.LinkExists(configDirPath, entry.File)
+}
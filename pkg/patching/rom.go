@@ -1,66 +1,174 @@
 package patching
 
 import (
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// DefaultArticles returns the articles, per language, which naming conventions such as No-Intro
+// move from the start of a title to the end i.e. "The Simpsons" becomes "Simpsons, The"
+func DefaultArticles() map[string][]string {
+	return map[string][]string{
+		"de": {"die", "der", "das"},
+		"en": {"the", "a", "an"},
+		"es": {"el", "los"},
+		"fr": {"le", "la", "les"},
+		"it": {"il"},
+	}
+}
+
+// defaultArticles is the article lookup used for ROMs built without a patcher
+var defaultArticles = newArticleSet(DefaultArticles())
+
+// WithArticles sets the articles, per language, which may be moved between the start and the end
+// of a title. Languages are the two letter codes used by No-Intro i.e. "en" or "de".
+func WithArticles(articles map[string][]string) Option {
+	return func(p *Patcher) {
+		p.articles = newArticleSet(articles)
+	}
+}
+
+// articleSet is the lookup of the articles which are relocated when working out alternate names
+type articleSet struct {
+	// all holds the articles of every language, in a stable order
+	all []string
+	// byLanguage holds the articles of each language
+	byLanguage map[string][]string
+}
+
+// newArticleSet builds the article lookup for the given articles
+func newArticleSet(articles map[string][]string) *articleSet {
+	languages := make([]string, 0, len(articles))
+	for language := range articles {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	set := &articleSet{byLanguage: map[string][]string{}}
+	for _, language := range languages {
+		code := strings.ToLower(language)
+		for _, article := range articles[language] {
+			article = strings.ToLower(article)
+			set.all = append(set.all, article)
+			set.byLanguage[code] = append(set.byLanguage[code], article)
+		}
+		set.byLanguage[code] = uniqueItems(set.byLanguage[code])
+	}
+	set.all = uniqueItems(set.all)
+	return set
+}
+
+// articleSet returns the article lookup used to build ROMs
+func (p *Patcher) articleSet() *articleSet {
+	if p.articles == nil {
+		return defaultArticles
+	}
+	return p.articles
+}
+
 // Rom is used to hold information about a file
 type Rom struct {
-	FileName       string
-	Name           string
-	AlternateNames []string
+	FileName        string
+	Path            string
+	Name            string
+	AlternateNames  []string
+	ArchiveContents []string
+	// CanonicalName is the ROM's name in the DAT, if it was resolved through a DAT
+	CanonicalName string
+	Tags
+
+	articles *articleSet
 }
 
 // NewRom builds a new ROM and works out all the alternate names
 func NewRom(fileName string) *Rom {
-	baseName := getBaseName(fileName)
+	return defaultArticles.newRom(fileName)
+}
+
+// NewNestedRom builds a new ROM for a file found in a subdirectory. The path should be relative
+// to the ROM directory.
+func NewNestedRom(path string) *Rom {
+	return defaultArticles.newNestedRom(path)
+}
+
+// newRom builds a new ROM and works out all the alternate names using the articles
+func (a *articleSet) newRom(fileName string) *Rom {
+	tags := ParseTags(fileName)
+	baseName := strings.ToLower(tags.Title)
 	return &Rom{
 		FileName:       fileName,
+		Path:           fileName,
 		Name:           baseName,
-		AlternateNames: getAlternateNames(baseName, true),
+		AlternateNames: a.alternateNames(baseName, tags.titleLanguages(), true),
+		Tags:           tags,
+		articles:       a,
 	}
 }
 
-// ConfigName returns the config name that should be used
-func (r *Rom) ConfigName() string {
-	return strings.TrimSuffix(r.FileName, filepath.Ext(r.FileName)) + ".cfg"
+// newNestedRom builds a new ROM for a file found in a subdirectory using the articles
+func (a *articleSet) newNestedRom(path string) *Rom {
+	rom := a.newRom(filepath.Base(path))
+	rom.Path = path
+	return rom
 }
 
-// getBaseName returns the name of the file minus any extensions and tags i.e. (U), [!] etc
-func getBaseName(fileName string) string {
-	// strip any file extensions or tags {
-	for _, substr := range []string{".", "[", "("} {
-		if i := strings.Index(fileName, substr); i > 0 {
-			fileName = fileName[:i]
-		}
+// AddArchiveContents records the files found inside the ROM's archive and adds their names as
+// alternate names so they can be matched against the config files
+func (r *Rom) AddArchiveContents(filePaths []string) {
+	articles := r.articleSet()
+	for _, filePath := range filePaths {
+		// archives may contain directories and always use forward slashes
+		fileName := path.Base(filePath)
+		tags := ParseTags(fileName)
+		r.ArchiveContents = append(r.ArchiveContents, fileName)
+		r.AlternateNames = append(r.AlternateNames, articles.alternateNames(tags.Title, tags.titleLanguages(), true)...)
 	}
+	r.AlternateNames = uniqueItems(r.AlternateNames)
+}
 
-	return strings.ToLower(strings.TrimSpace(fileName))
+// articleSet returns the article lookup the ROM was built with
+func (r *Rom) articleSet() *articleSet {
+	if r.articles == nil {
+		return defaultArticles
+	}
+	return r.articles
 }
 
-// getAlternateNames works out all the possible alternate names for the given name
-func getAlternateNames(name string, recursive bool) []string {
+// ConfigName returns the config name that should be used. RetroArch only uses the content's
+// file name so nested ROMs use the same config name as they would at the top level.
+func (r *Rom) ConfigName() string {
+	return strings.TrimSuffix(r.FileName, filepath.Ext(r.FileName)) + ".cfg"
+}
+
+// alternateNames works out all the possible alternate names for the given name. Articles at the
+// end of the name are always moved to the start, as are English articles at the start of the name.
+// Other articles at the start are only moved to the end for the languages of the title as words
+// such as "die" also start English titles.
+func (a *articleSet) alternateNames(name string, languages []string, recursive bool) []string {
 	name = strings.ToLower(name)
 	alternates := []string{name}
 
-	// the no-intro ROM naming convention moves the "the" to the end of the ROM name but
+	// the no-intro ROM naming convention moves the article to the end of the ROM name but
 	// before any suffix's. e.g. "the simpsons - ultimate" would become "simpsons, the - ultimate"
-	if strings.HasPrefix(name, "the ") || strings.Contains(name, ", the") {
-		var correctedName string
-		if strings.HasPrefix(name, "the ") {
-			// some roms have a suffix and the "the" should be placed before this suffix
-			nameParts := strings.Split(name[4:], " - ")
-			nameParts[0] += ", the"
-			correctedName = strings.Join(nameParts, " - ")
-		} else {
-			i := strings.Index(name, ", the")
-			correctedName = "the " + name[:i] + name[i+5:]
+	leadingArticles := append([]string{}, a.byLanguage["en"]...)
+	for _, language := range languages {
+		leadingArticles = append(leadingArticles, a.byLanguage[language]...)
+	}
+	for _, article := range a.all {
+		correctedName, ok := "", false
+		if containsItem(leadingArticles, article) {
+			correctedName, ok = moveArticleToEnd(name, article)
 		}
-
-		alternates = append(alternates, correctedName)
-		if recursive {
-			alternates = append(alternates, getAlternateNames(correctedName, false)...)
+		if !ok {
+			correctedName, ok = moveArticleToStart(name, article)
+		}
+		if ok {
+			alternates = append(alternates, correctedName)
+			if recursive {
+				alternates = append(alternates, a.alternateNames(correctedName, languages, false)...)
+			}
 		}
 	}
 
@@ -72,6 +180,39 @@ func getAlternateNames(name string, recursive bool) []string {
 	return uniqueItems(alternates)
 }
 
+// moveArticleToEnd moves the article from the start of the name to the end of the name. The end
+// of the name is before any suffix so "the simpsons - ultimate" is "simpsons, the - ultimate". It
+// returns false if the name does not start with the article.
+func moveArticleToEnd(name, article string) (string, bool) {
+	if !strings.HasPrefix(name, article+" ") {
+		return "", false
+	}
+	// some roms have a suffix and the article should be placed before this suffix
+	nameParts := strings.SplitN(name[len(article)+1:], " - ", 2)
+	nameParts[0] += ", " + article
+	return strings.Join(nameParts, " - "), true
+}
+
+// moveArticleToStart moves the article from the end of the name back to the start. The end of the
+// name is before any suffix so "simpsons, the - ultimate" is "the simpsons - ultimate". It returns
+// false if the name does not end with the article.
+func moveArticleToStart(name, article string) (string, bool) {
+	// the article must be a whole word i.e. ", a" should not match ", an"
+	suffix := ", " + article
+	for offset := 0; offset < len(name); {
+		i := strings.Index(name[offset:], suffix)
+		if i < 0 {
+			break
+		}
+		start, end := offset+i, offset+i+len(suffix)
+		if end == len(name) || strings.HasPrefix(name[end:], " - ") {
+			return article + " " + name[:start] + name[end:], true
+		}
+		offset = end
+	}
+	return "", false
+}
+
 // uniqueItems returns only the unique items from a slice
 func uniqueItems(slice []string) []string {
 	uniqueSlice := []string{}
@@ -23,6 +23,11 @@ func TestRomParse(t *testing.T) {
 					"tony hawk's collection, the",
 					"tony hawks collection, the",
 				},
+				Tags: Tags{
+					Title:     "Tony Hawk's Collection, The",
+					Regions:   []string{"USA"},
+					DumpFlags: []string{"!"},
+				},
 			},
 		},
 		"no-intro rom name": {
@@ -37,6 +42,11 @@ func TestRomParse(t *testing.T) {
 					"tony hawk's collection, the",
 					"tony hawks collection, the",
 				},
+				Tags: Tags{
+					Title:     "Tony Hawk's Collection, The",
+					Regions:   []string{"USA"},
+					DumpFlags: []string{"!"},
+				},
 			},
 		},
 		"standard rom name with suffix": {
@@ -51,6 +61,11 @@ func TestRomParse(t *testing.T) {
 					"tony hawk's collection, the - ultimate edition",
 					"tony hawks collection, the - ultimate edition",
 				},
+				Tags: Tags{
+					Title:     "The Tony Hawk's Collection - Ultimate Edition",
+					Regions:   []string{"USA"},
+					DumpFlags: []string{"!"},
+				},
 			},
 		},
 		"no-intro rom name with suffix": {
@@ -65,6 +80,305 @@ func TestRomParse(t *testing.T) {
 					"tony hawk's collection, the - ultimate edition",
 					"tony hawks collection, the - ultimate edition",
 				},
+				Tags: Tags{
+					Title:     "Tony Hawk's Collection, The - Ultimate Edition",
+					Regions:   []string{"USA"},
+					DumpFlags: []string{"!"},
+				},
+			},
+		},
+		"english a": {
+			fileName: "Bug's Life, A (USA).n64",
+			expectedRom: &Rom{
+				FileName: "Bug's Life, A (USA).n64",
+				Name:     "bug's life, a",
+				AlternateNames: []string{
+					"a bug's life",
+					"a bugs life",
+
+					"bug's life, a",
+					"bugs life, a",
+				},
+				Tags: Tags{
+					Title:   "Bug's Life, A",
+					Regions: []string{"USA"},
+				},
+			},
+		},
+		"english an with suffix": {
+			fileName: "An American Tail - Fievel Goes West (USA).sfc",
+			expectedRom: &Rom{
+				FileName: "An American Tail - Fievel Goes West (USA).sfc",
+				Name:     "an american tail - fievel goes west",
+				AlternateNames: []string{
+					"an american tail - fievel goes west",
+					"american tail, an - fievel goes west",
+				},
+				Tags: Tags{
+					Title:   "An American Tail - Fievel Goes West",
+					Regions: []string{"USA"},
+				},
+			},
+		},
+		"english article must be a whole word": {
+			fileName: "Batman, Animated Series (USA).sfc",
+			expectedRom: &Rom{
+				FileName:       "Batman, Animated Series (USA).sfc",
+				Name:           "batman, animated series",
+				AlternateNames: []string{"batman, animated series"},
+				Tags: Tags{
+					Title:   "Batman, Animated Series",
+					Regions: []string{"USA"},
+				},
+			},
+		},
+		"french le": {
+			fileName: "Petit Prince, Le (France).gba",
+			expectedRom: &Rom{
+				FileName: "Petit Prince, Le (France).gba",
+				Name:     "petit prince, le",
+				AlternateNames: []string{
+					"petit prince, le",
+					"le petit prince",
+				},
+				Tags: Tags{
+					Title:   "Petit Prince, Le",
+					Regions: []string{"France"},
+				},
+			},
+		},
+		"french la": {
+			fileName: "La Planete des Singes (France).gba",
+			expectedRom: &Rom{
+				FileName: "La Planete des Singes (France).gba",
+				Name:     "la planete des singes",
+				AlternateNames: []string{
+					"la planete des singes",
+					"planete des singes, la",
+				},
+				Tags: Tags{
+					Title:   "La Planete des Singes",
+					Regions: []string{"France"},
+				},
+			},
+		},
+		"french les with suffix": {
+			fileName: "Schtroumpfs, Les - Autour du Monde (Europe) (Fr).gbc",
+			expectedRom: &Rom{
+				FileName: "Schtroumpfs, Les - Autour du Monde (Europe) (Fr).gbc",
+				Name:     "schtroumpfs, les - autour du monde",
+				AlternateNames: []string{
+					"schtroumpfs, les - autour du monde",
+					"les schtroumpfs - autour du monde",
+				},
+				Tags: Tags{
+					Title:     "Schtroumpfs, Les - Autour du Monde",
+					Regions:   []string{"Europe"},
+					Languages: []string{"Fr"},
+				},
+			},
+		},
+		"german die": {
+			fileName: "Siedler, Die (Germany).adf",
+			expectedRom: &Rom{
+				FileName: "Siedler, Die (Germany).adf",
+				Name:     "siedler, die",
+				AlternateNames: []string{
+					"siedler, die",
+					"die siedler",
+				},
+				Tags: Tags{
+					Title:   "Siedler, Die",
+					Regions: []string{"Germany"},
+				},
+			},
+		},
+		"german der": {
+			fileName: "Der Patrizier (Germany).adf",
+			expectedRom: &Rom{
+				FileName: "Der Patrizier (Germany).adf",
+				Name:     "der patrizier",
+				AlternateNames: []string{
+					"der patrizier",
+					"patrizier, der",
+				},
+				Tags: Tags{
+					Title:   "Der Patrizier",
+					Regions: []string{"Germany"},
+				},
+			},
+		},
+		"german das with suffix": {
+			fileName: "Schwarze Auge, Das - Sternenschweif (Germany).adf",
+			expectedRom: &Rom{
+				FileName: "Schwarze Auge, Das - Sternenschweif (Germany).adf",
+				Name:     "schwarze auge, das - sternenschweif",
+				AlternateNames: []string{
+					"schwarze auge, das - sternenschweif",
+					"das schwarze auge - sternenschweif",
+				},
+				Tags: Tags{
+					Title:   "Schwarze Auge, Das - Sternenschweif",
+					Regions: []string{"Germany"},
+				},
+			},
+		},
+		"spanish el": {
+			fileName: "Gato con Botas, El (Spain).gba",
+			expectedRom: &Rom{
+				FileName: "Gato con Botas, El (Spain).gba",
+				Name:     "gato con botas, el",
+				AlternateNames: []string{
+					"gato con botas, el",
+					"el gato con botas",
+				},
+				Tags: Tags{
+					Title:   "Gato con Botas, El",
+					Regions: []string{"Spain"},
+				},
+			},
+		},
+		"spanish los": {
+			fileName: "Los Justicieros (Spain).md",
+			expectedRom: &Rom{
+				FileName: "Los Justicieros (Spain).md",
+				Name:     "los justicieros",
+				AlternateNames: []string{
+					"los justicieros",
+					"justicieros, los",
+				},
+				Tags: Tags{
+					Title:   "Los Justicieros",
+					Regions: []string{"Spain"},
+				},
+			},
+		},
+		"italian il with suffix": {
+			fileName: "Signore degli Anelli, Il - Le Due Torri (Italy).gba",
+			expectedRom: &Rom{
+				FileName: "Signore degli Anelli, Il - Le Due Torri (Italy).gba",
+				Name:     "signore degli anelli, il - le due torri",
+				AlternateNames: []string{
+					"signore degli anelli, il - le due torri",
+					"il signore degli anelli - le due torri",
+				},
+				Tags: Tags{
+					Title:   "Signore degli Anelli, Il - Le Due Torri",
+					Regions: []string{"Italy"},
+				},
+			},
+		},
+		"title containing a period": {
+			fileName: "Dr. Mario (World) (Rev A).nes",
+			expectedRom: &Rom{
+				FileName:       "Dr. Mario (World) (Rev A).nes",
+				Name:           "dr. mario",
+				AlternateNames: []string{"dr. mario"},
+				Tags: Tags{
+					Title:    "Dr. Mario",
+					Regions:  []string{"World"},
+					Revision: "A",
+				},
+			},
+		},
+		"title with a version": {
+			fileName: "Super Mario 64 v1.1 (U).z64",
+			expectedRom: &Rom{
+				FileName:       "Super Mario 64 v1.1 (U).z64",
+				Name:           "super mario 64",
+				AlternateNames: []string{"super mario 64"},
+				Tags: Tags{
+					Title:   "Super Mario 64",
+					Regions: []string{"USA"},
+					Version: "1.1",
+				},
+			},
+		},
+		"no-intro regions, languages and revision": {
+			fileName: "Legend of Zelda, The - A Link to the Past (Europe) (En,Fr,De) (Rev 1).sfc",
+			expectedRom: &Rom{
+				FileName: "Legend of Zelda, The - A Link to the Past (Europe) (En,Fr,De) (Rev 1).sfc",
+				Name:     "legend of zelda, the - a link to the past",
+				AlternateNames: []string{
+					"legend of zelda, the - a link to the past",
+					"the legend of zelda - a link to the past",
+				},
+				Tags: Tags{
+					Title:     "Legend of Zelda, The - A Link to the Past",
+					Regions:   []string{"Europe"},
+					Languages: []string{"En", "Fr", "De"},
+					Revision:  "1",
+				},
+			},
+		},
+		"no-intro multiple regions": {
+			fileName: "Sonic The Hedgehog (USA, Europe) (v1.1).md",
+			expectedRom: &Rom{
+				FileName:       "Sonic The Hedgehog (USA, Europe) (v1.1).md",
+				Name:           "sonic the hedgehog",
+				AlternateNames: []string{"sonic the hedgehog"},
+				Tags: Tags{
+					Title:   "Sonic The Hedgehog",
+					Regions: []string{"USA", "Europe"},
+					Version: "1.1",
+				},
+			},
+		},
+		"goodtools combined region and dump flags": {
+			fileName: "Mario Kart 64 (JU) (V1.1) [b1][h2C].v64",
+			expectedRom: &Rom{
+				FileName:       "Mario Kart 64 (JU) (V1.1) [b1][h2C].v64",
+				Name:           "mario kart 64",
+				AlternateNames: []string{"mario kart 64"},
+				Tags: Tags{
+					Title:     "Mario Kart 64",
+					Regions:   []string{"Japan", "USA"},
+					Version:   "1.1",
+					DumpFlags: []string{"b", "h"},
+				},
+			},
+		},
+		"goodtools translation": {
+			fileName: "Mother 3 (J) [T+Eng1.2].gba",
+			expectedRom: &Rom{
+				FileName:       "Mother 3 (J) [T+Eng1.2].gba",
+				Name:           "mother 3",
+				AlternateNames: []string{"mother 3"},
+				Tags: Tags{
+					Title:     "Mother 3",
+					Regions:   []string{"Japan"},
+					DumpFlags: []string{"T"},
+				},
+			},
+		},
+		"tosec": {
+			fileName: "Legend of Zelda, The (1986)(Nintendo)(US)(en)[cr Team][a2].nes",
+			expectedRom: &Rom{
+				FileName: "Legend of Zelda, The (1986)(Nintendo)(US)(en)[cr Team][a2].nes",
+				Name:     "legend of zelda, the",
+				AlternateNames: []string{
+					"legend of zelda, the",
+					"the legend of zelda",
+				},
+				Tags: Tags{
+					Title:     "Legend of Zelda, The",
+					Regions:   []string{"USA"},
+					Languages: []string{"En"},
+					DumpFlags: []string{"cr", "a"},
+				},
+			},
+		},
+		"tosec multiple countries": {
+			fileName: "Lemmings (1991)(Psygnosis)(DE-GB)(de-en).adf",
+			expectedRom: &Rom{
+				FileName:       "Lemmings (1991)(Psygnosis)(DE-GB)(de-en).adf",
+				Name:           "lemmings",
+				AlternateNames: []string{"lemmings"},
+				Tags: Tags{
+					Title:     "Lemmings",
+					Regions:   []string{"Germany", "United Kingdom"},
+					Languages: []string{"De", "En"},
+				},
 			},
 		},
 	}
@@ -75,6 +389,12 @@ func TestRomParse(t *testing.T) {
 			assert.Equal(t, tc.expectedRom.FileName, rom.FileName)
 			assert.Equal(t, tc.expectedRom.Name, rom.Name)
 			assert.ElementsMatch(t, tc.expectedRom.AlternateNames, rom.AlternateNames)
+			assert.Equal(t, tc.expectedRom.Title, rom.Title)
+			assert.ElementsMatch(t, tc.expectedRom.Regions, rom.Regions)
+			assert.ElementsMatch(t, tc.expectedRom.Languages, rom.Languages)
+			assert.Equal(t, tc.expectedRom.Revision, rom.Revision)
+			assert.Equal(t, tc.expectedRom.Version, rom.Version)
+			assert.ElementsMatch(t, tc.expectedRom.DumpFlags, rom.DumpFlags)
 		})
 	}
 
@@ -85,3 +405,80 @@ func TestSameRomWithDifferntNameFormatMatchAlternates(t *testing.T) {
 	rom2 := NewRom("Tony Hawk's Collection, The (USA) [!].n64")
 	assert.ElementsMatch(t, rom1.AlternateNames, rom2.AlternateNames)
 }
+
+func TestArticlesAreConfigurable(t *testing.T) {
+	articles := NewPatcher(nil, false, WithArticles(map[string][]string{"nl": {"De", "Het"}})).articleSet()
+	assert.ElementsMatch(t, []string{"vliegende hollander, de", "de vliegende hollander"}, articles.newRom("Vliegende Hollander, De (Netherlands).gba").AlternateNames)
+	assert.ElementsMatch(t, []string{"de vliegende hollander", "vliegende hollander, de"}, articles.newRom("De Vliegende Hollander (Netherlands).gba").AlternateNames)
+	assert.ElementsMatch(t, []string{"simpsons, the"}, articles.newRom("Simpsons, The (USA).sfc").AlternateNames)
+
+	// ROMs found in archives and resolved through a DAT use the same articles
+	rom := articles.newRom("hollander.zip")
+	rom.AddArchiveContents([]string{"Vliegende Hollander, De (Netherlands).gba"})
+	assert.Contains(t, rom.AlternateNames, "de vliegende hollander")
+}
+
+func TestLeadingArticlesFollowTheTitleLanguage(t *testing.T) {
+	tt := map[string]struct {
+		fileName               string
+		expectedAlternateNames []string
+	}{
+		"english title starting with a german article": {
+			fileName:               "Die Hard Trilogy (USA).psx",
+			expectedAlternateNames: []string{"die hard trilogy"},
+		},
+		"english articles are moved for japanese titles": {
+			fileName:               "The Legend of Zelda (J).nes",
+			expectedAlternateNames: []string{"the legend of zelda", "legend of zelda, the"},
+		},
+		"english articles are moved for german titles": {
+			fileName:               "The Simpsons (Germany).sfc",
+			expectedAlternateNames: []string{"the simpsons", "simpsons, the"},
+		},
+		"english articles are moved for french titles": {
+			fileName:               "The Lion King (F).sfc",
+			expectedAlternateNames: []string{"the lion king", "lion king, the"},
+		},
+		"untagged titles only move english articles": {
+			fileName:               "Die Hard Trilogy.zip",
+			expectedAlternateNames: []string{"die hard trilogy"},
+		},
+		"german title from its region": {
+			fileName:               "Die Siedler (Germany).adf",
+			expectedAlternateNames: []string{"die siedler", "siedler, die"},
+		},
+		"german title from its languages": {
+			fileName:               "Die Siedler (Europe) (De).adf",
+			expectedAlternateNames: []string{"die siedler", "siedler, die"},
+		},
+		"trailing articles are moved for any language": {
+			fileName:               "Siedler, Die (USA).adf",
+			expectedAlternateNames: []string{"siedler, die", "die siedler"},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			assert.ElementsMatch(t, tc.expectedAlternateNames, NewRom(tc.fileName).AlternateNames)
+		})
+	}
+}
+
+func TestArchiveContentsAddAlternateNames(t *testing.T) {
+	rom := NewRom("tetris.zip")
+	rom.AddArchiveContents([]string{"roms/New Tetris, The (USA).n64"})
+	assert.Equal(t, []string{"New Tetris, The (USA).n64"}, rom.ArchiveContents)
+	assert.ElementsMatch(t, []string{"tetris", "new tetris, the", "the new tetris"}, rom.AlternateNames)
+}
+
+func TestDumpFlags(t *testing.T) {
+	rom := NewRom("Super Mario Bros. (W) [!].nes")
+	assert.True(t, rom.Verified())
+	assert.False(t, rom.Bad())
+	assert.False(t, rom.Hacked())
+
+	rom = NewRom("Super Mario Bros. (W) [b1][h1].nes")
+	assert.False(t, rom.Verified())
+	assert.True(t, rom.Bad())
+	assert.True(t, rom.Hacked())
+}
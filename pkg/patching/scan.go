@@ -0,0 +1,165 @@
+package patching
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// scanRomDirectory builds a ROM for every file in the ROM directory, including the contents of
+// any archives if archive inspection is enabled. Files which are not ROMs are returned separately
+// along with the reason they were ignored.
+func (p *Patcher) scanRomDirectory(romDirPath string) (roms []*Rom, ignored map[string]string, err error) {
+	allRoms, err := p.listRoms(romDirPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// checking and inspecting each file is independent so it is shared between the workers, the
+	// results are then collected in the original order
+	reasons := make([]string, len(allRoms))
+	p.forEach(len(allRoms), func(i int) {
+		reasons[i] = p.ignoreReason(romDirPath, allRoms[i])
+		if reasons[i] == "" && p.inspectArchives {
+			p.inspectArchive(romDirPath, allRoms[i])
+		}
+	})
+
+	roms = []*Rom{}
+	ignored = map[string]string{}
+	for i, rom := range allRoms {
+		if reasons[i] != "" {
+			ignored[rom.Path] = reasons[i]
+			continue
+		}
+		roms = append(roms, rom)
+	}
+
+	return roms, ignored, nil
+}
+
+// listRoms builds a ROM for every file in the ROM directory. When scanning recursively,
+// subdirectories are walked and any files which are only loaded through a playlist (.m3u) or
+// cue sheet (.cue) are left out as RetroArch never loads them directly.
+func (p *Patcher) listRoms(romDirPath string) ([]*Rom, error) {
+	if !p.recursive {
+		romDirFiles, err := p.fileManager.GetDirectoryContents(romDirPath)
+		if err != nil {
+			return nil, err
+		}
+		roms := make([]*Rom, len(romDirFiles))
+		for i, item := range romDirFiles {
+			roms[i] = p.articleSet().newRom(item)
+		}
+		return roms, nil
+	}
+
+	romDirFiles, err := p.fileManager.GetDirectoryContentsRecursive(romDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make([][]string, len(romDirFiles))
+	p.forEach(len(romDirFiles), func(i int) {
+		references[i] = p.referencedFiles(romDirPath, romDirFiles[i])
+	})
+	referencedFiles := map[string]bool{}
+	for _, itemReferences := range references {
+		for _, referencedFile := range itemReferences {
+			referencedFiles[strings.ToLower(referencedFile)] = true
+		}
+	}
+
+	roms := []*Rom{}
+	for _, item := range romDirFiles {
+		if referencedFiles[strings.ToLower(filepath.Clean(item))] {
+			continue
+		}
+		roms = append(roms, p.articleSet().newNestedRom(item))
+	}
+	return roms, nil
+}
+
+// isZipArchive returns true if the file is a zip archive
+func isZipArchive(fileName string) bool {
+	return strings.ToLower(filepath.Ext(fileName)) == ".zip"
+}
+
+// inspectArchive adds the contents of a zip archive to the ROM. Archives which cannot be read are
+// left as they are so the ROM can still be matched on its own file name. Only zip archives are
+// supported.
+func (p *Patcher) inspectArchive(romDirPath string, rom *Rom) {
+	if !isZipArchive(rom.FileName) {
+		return
+	}
+	contents, err := p.fileManager.GetArchiveContents(romDirPath, rom.Path)
+	if err != nil {
+		return
+	}
+	rom.AddArchiveContents(contents)
+}
+
+// referencedFiles returns the paths of the files referenced by a playlist or cue sheet, relative
+// to the ROM directory. Any other type of file does not reference anything.
+func (p *Patcher) referencedFiles(romDirPath, path string) []string {
+	var parse func([]byte) []string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u":
+		parse = parsePlaylist
+	case ".cue":
+		parse = parseCueSheet
+	default:
+		return []string{}
+	}
+
+	contents, err := p.fileManager.ReadFile(romDirPath, path)
+	if err != nil {
+		return []string{}
+	}
+
+	// references are relative to the file they are found in
+	referencedFiles := []string{}
+	for _, referencedFile := range parse(contents) {
+		referencedFiles = append(referencedFiles, filepath.Join(filepath.Dir(path), filepath.FromSlash(referencedFile)))
+	}
+	return referencedFiles
+}
+
+// parsePlaylist returns the files listed in an m3u playlist
+func parsePlaylist(contents []byte) []string {
+	files := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		files = append(files, line)
+	}
+	return files
+}
+
+// parseCueSheet returns the files referenced by the FILE commands in a cue sheet
+func parseCueSheet(contents []byte) []string {
+	files := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(strings.ToUpper(line), "FILE ") {
+			continue
+		}
+		line = strings.TrimSpace(line[5:])
+		if strings.HasPrefix(line, "\"") {
+			if i := strings.Index(line[1:], "\""); i >= 0 {
+				files = append(files, line[1:i+1])
+			}
+			continue
+		}
+		// unquoted file names cannot contain spaces so the type follows the first space
+		if fields := strings.Fields(line); len(fields) > 0 {
+			files = append(files, fields[0])
+		}
+	}
+	return files
+}
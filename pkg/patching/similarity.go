@@ -0,0 +1,167 @@
+package patching
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultMinimumScore is the lowest similarity score which counts as a scored match when no
+// minimum score has been set
+const DefaultMinimumScore = 0.85
+
+// romanNumerals maps the roman numerals commonly found in titles to their arabic equivalent
+var romanNumerals = map[string]string{
+	"i": "1", "ii": "2", "iii": "3", "iv": "4", "v": "5", "vi": "6", "vii": "7", "viii": "8",
+	"ix": "9", "x": "10", "xi": "11", "xii": "12", "xiii": "13", "xiv": "14", "xv": "15",
+}
+
+// WithMinimumScore sets the lowest similarity score, between 0 and 1, which counts as a scored match.
+// It applies to any ScoredMatcher in the matcher chain which does not have its own minimum score.
+func WithMinimumScore(score float64) Option {
+	return func(p *Patcher) {
+		p.minimumScore = score
+	}
+}
+
+// scoreThreshold returns the minimum score a scored match must reach
+func (p *Patcher) scoreThreshold() float64 {
+	if p.minimumScore <= 0 {
+		return DefaultMinimumScore
+	}
+	return p.minimumScore
+}
+
+// bestSimilarity returns the highest similarity score between any of the ROM's alternate names
+// and any of the config's alternate names
+func bestSimilarity(configFile, rom *Rom) float64 {
+	best := 0.0
+	for _, romAlternateName := range rom.AlternateNames {
+		for _, configAlternateName := range configFile.AlternateNames {
+			if score := similarity(romAlternateName, configAlternateName); score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
+
+// similarity scores how alike two names are between 0 and 1. Both names are normalised into
+// tokens first so punctuation, "&" and roman numerals make no difference, then the tokens are
+// compared in order and as a sorted set so differences in word order are also forgiven. Names
+// with different numbers are sequels, or different years, of a game so they never score.
+func similarity(a, b string) float64 {
+	aTokens, bTokens := tokenise(a), tokenise(b)
+	if len(aTokens) == 0 || len(bTokens) == 0 {
+		return 0
+	}
+	if !equalItems(numbers(aTokens), numbers(bTokens)) {
+		return 0
+	}
+
+	score := editSimilarity(strings.Join(aTokens, ""), strings.Join(bTokens, ""))
+	if setScore := editSimilarity(strings.Join(tokenSet(aTokens), ""), strings.Join(tokenSet(bTokens), "")); setScore > score {
+		score = setScore
+	}
+	return score
+}
+
+// tokenise splits a name into lower case words with any punctuation removed
+func tokenise(name string) []string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "&", " and ")
+	// apostrophes are part of the word they are in i.e. "hawk's" is "hawks"
+	name = strings.ReplaceAll(name, "'", "")
+
+	tokens := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, token := range tokens {
+		if numeral, ok := romanNumerals[token]; ok {
+			tokens[i] = numeral
+		}
+	}
+	return tokens
+}
+
+// numbers returns the unique numeric tokens in order, without any leading zeros i.e. "007" is "7"
+func numbers(tokens []string) []string {
+	found := []string{}
+	for _, token := range tokens {
+		if strings.Trim(token, "0123456789") != "" {
+			continue
+		}
+		if number := strings.TrimLeft(token, "0"); number != "" {
+			token = number
+		} else {
+			token = "0"
+		}
+		found = append(found, token)
+	}
+	return tokenSet(found)
+}
+
+// equalItems returns true if both slices contain the same items in the same order
+func equalItems(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenSet returns the unique tokens in alphabetical order
+func tokenSet(tokens []string) []string {
+	set := uniqueItems(tokens)
+	sort.Strings(set)
+	return set
+}
+
+// editSimilarity converts the edit distance between two strings into a score between 0 and 1
+func editSimilarity(a, b string) float64 {
+	longest := len([]rune(a))
+	if l := len([]rune(b)); l > longest {
+		longest = l
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// levenshtein returns the number of single character edits needed to turn one string into the other
+func levenshtein(a, b string) int {
+	aRunes, bRunes := []rune(a), []rune(b)
+	previous := make([]int, len(bRunes)+1)
+	current := make([]int, len(bRunes)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(aRunes); i++ {
+		current[0] = i
+		for j := 1; j <= len(bRunes); j++ {
+			cost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(bRunes)]
+}
+
+// minInt returns the smallest of the given numbers
+func minInt(values ...int) int {
+	smallest := values[0]
+	for _, value := range values[1:] {
+		if value < smallest {
+			smallest = value
+		}
+	}
+	return smallest
+}
@@ -0,0 +1,64 @@
+package patching
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarity(t *testing.T) {
+	tt := map[string]struct {
+		a, b             string
+		expectedMinScore float64
+		expectedMaxScore float64
+	}{
+		"identical": {
+			a: "wave race 64", b: "wave race 64",
+			expectedMinScore: 1, expectedMaxScore: 1,
+		},
+		"hyphenated": {
+			a: "spider-man", b: "spiderman",
+			expectedMinScore: 1, expectedMaxScore: 1,
+		},
+		"ampersand": {
+			a: "banjo & kazooie", b: "banjo and kazooie",
+			expectedMinScore: 1, expectedMaxScore: 1,
+		},
+		"roman numerals": {
+			a: "final fantasy vii", b: "final fantasy 7",
+			expectedMinScore: 1, expectedMaxScore: 1,
+		},
+		"word order": {
+			a: "mario kart 64", b: "64 mario kart",
+			expectedMinScore: 1, expectedMaxScore: 1,
+		},
+		"typo": {
+			a: "goldeneye 007", b: "goldeneye 07",
+			expectedMinScore: 0.9, expectedMaxScore: 0.95,
+		},
+		"different sequels": {
+			a: "final fantasy vii", b: "final fantasy viii",
+			expectedMinScore: 0, expectedMaxScore: 0,
+		},
+		"different numbered sequels": {
+			a: "super mario bros. 2", b: "super mario bros. 3",
+			expectedMinScore: 0, expectedMaxScore: 0,
+		},
+		"sequel of an unnumbered game": {
+			a: "super mario bros.", b: "super mario bros. 2",
+			expectedMinScore: 0, expectedMaxScore: 0,
+		},
+		"different games": {
+			a: "mario kart 64", b: "wave race 64",
+			expectedMinScore: 0, expectedMaxScore: 0.5,
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			score := similarity(tc.a, tc.b)
+			assert.GreaterOrEqual(t, score, tc.expectedMinScore)
+			assert.LessOrEqual(t, score, tc.expectedMaxScore)
+		})
+	}
+}
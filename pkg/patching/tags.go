@@ -0,0 +1,300 @@
+package patching
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Tags holds the information encoded in a file name by the No-Intro, TOSEC and GoodTools naming
+// conventions i.e. "Title v1.1 (USA, Europe) (En,Fr) (Rev 1) [!]"
+type Tags struct {
+	Title     string
+	Regions   []string
+	Languages []string
+	Revision  string
+	Version   string
+	DumpFlags []string
+}
+
+const (
+	// DumpFlagVerified marks a dump which is known to be good ([!])
+	DumpFlagVerified = "!"
+	// DumpFlagBad marks a bad dump ([b])
+	DumpFlagBad = "b"
+	// DumpFlagHacked marks a hacked dump ([h])
+	DumpFlagHacked = "h"
+)
+
+var (
+	// titleVersionPattern matches a version at the end of a title i.e. "Super Mario 64 v1.1"
+	titleVersionPattern = regexp.MustCompile(`(?i)\s+v(\d+(?:\.\d+)*[a-z]?)$`)
+	// versionPattern matches a version tag i.e. "(v1.1)" or "(V1.0)"
+	versionPattern = regexp.MustCompile(`(?i)^v(\d+(?:\.\d+)*[a-z]?)$`)
+	// revisionPattern matches a revision tag i.e. "(Rev 1)", "(Rev A)" or "(REV01)"
+	revisionPattern = regexp.MustCompile(`(?i)^rev\s*([0-9a-z.]+)$`)
+	// goodToolsRegionPattern matches combined GoodTools region codes i.e. "(JU)" or "(UE)"
+	goodToolsRegionPattern = regexp.MustCompile(`^[UEJWKABCFGSI14]+$`)
+)
+
+// regionNames are the region names used by No-Intro
+var regionNames = toSet([]string{
+	"World", "USA", "Europe", "Japan", "Korea", "Asia", "Australia", "Brazil", "Canada", "China",
+	"Denmark", "Finland", "France", "Germany", "Greece", "Hong Kong", "Italy", "Latin America",
+	"Mexico", "Netherlands", "Norway", "Poland", "Portugal", "Russia", "Scandinavia", "Spain",
+	"Sweden", "Taiwan", "United Kingdom", "Unknown",
+})
+
+// goodToolsRegions maps GoodTools region codes to No-Intro region names
+var goodToolsRegions = map[string][]string{
+	"U":   {"USA"},
+	"E":   {"Europe"},
+	"J":   {"Japan"},
+	"W":   {"World"},
+	"K":   {"Korea"},
+	"A":   {"Australia"},
+	"B":   {"Brazil"},
+	"C":   {"China"},
+	"F":   {"France"},
+	"G":   {"Germany"},
+	"S":   {"Spain"},
+	"I":   {"Italy"},
+	"1":   {"Japan", "Korea"},
+	"4":   {"USA", "Brazil"},
+	"H":   {"Netherlands"},
+	"HK":  {"Hong Kong"},
+	"NL":  {"Netherlands"},
+	"UK":  {"United Kingdom"},
+	"Unk": {"Unknown"},
+}
+
+// tosecRegions maps TOSEC country codes to No-Intro region names
+var tosecRegions = map[string]string{
+	"AS": "Asia", "AU": "Australia", "BR": "Brazil", "CA": "Canada", "CN": "China",
+	"DE": "Germany", "DK": "Denmark", "ES": "Spain", "EU": "Europe", "FI": "Finland",
+	"FR": "France", "GB": "United Kingdom", "GR": "Greece", "HK": "Hong Kong", "IT": "Italy",
+	"JP": "Japan", "KR": "Korea", "NL": "Netherlands", "NO": "Norway", "PL": "Poland",
+	"PT": "Portugal", "RU": "Russia", "SE": "Sweden", "TW": "Taiwan", "US": "USA",
+}
+
+// languageCodes are the language codes used by No-Intro ("En,Fr") and TOSEC ("en-fr")
+var languageCodes = toSet([]string{
+	"ca", "cs", "da", "de", "el", "en", "es", "fi", "fr", "hu", "it", "ja", "ko", "nl", "no",
+	"pl", "pt", "ru", "sv", "tr", "zh",
+})
+
+// regionLanguages maps the No-Intro regions which only use a single language to that language
+var regionLanguages = map[string]string{
+	"Australia": "en", "Brazil": "pt", "China": "zh", "Denmark": "da", "Finland": "fi", "France": "fr",
+	"Germany": "de", "Greece": "el", "Italy": "it", "Japan": "ja", "Korea": "ko", "Mexico": "es",
+	"Netherlands": "nl", "Norway": "no", "Poland": "pl", "Portugal": "pt", "Russia": "ru", "Spain": "es",
+	"Sweden": "sv", "Taiwan": "zh", "United Kingdom": "en", "USA": "en",
+}
+
+// ParseTags parses a file name into its title and tags. Any extension is ignored, as are tags
+// which are not understood such as years, publishers and "(Beta)".
+func ParseTags(fileName string) Tags {
+	name := trimExtension(fileName)
+	tags := Tags{
+		Regions:   []string{},
+		Languages: []string{},
+		DumpFlags: []string{},
+	}
+
+	// everything before the first tag is the title
+	tagStart := len(name)
+	for _, substr := range []string{"(", "["} {
+		if i := strings.Index(name, substr); i > 0 && i < tagStart {
+			tagStart = i
+		}
+	}
+	tags.Title = strings.TrimSpace(name[:tagStart])
+	if match := titleVersionPattern.FindStringSubmatch(tags.Title); match != nil {
+		tags.Version = match[1]
+		tags.Title = strings.TrimSpace(tags.Title[:len(tags.Title)-len(match[0])])
+	}
+
+	for _, group := range tagGroups(name[tagStart:]) {
+		if group.bracket == '[' {
+			if flag := dumpFlag(group.value); flag != "" && !containsItem(tags.DumpFlags, flag) {
+				tags.DumpFlags = append(tags.DumpFlags, flag)
+			}
+			continue
+		}
+		tags.parseGroup(group.value)
+	}
+
+	return tags
+}
+
+// Verified returns true if the dump is known to be good
+func (t Tags) Verified() bool {
+	return containsItem(t.DumpFlags, DumpFlagVerified)
+}
+
+// Bad returns true if the dump is known to be bad
+func (t Tags) Bad() bool {
+	return containsItem(t.DumpFlags, DumpFlagBad)
+}
+
+// Hacked returns true if the dump has been hacked
+func (t Tags) Hacked() bool {
+	return containsItem(t.DumpFlags, DumpFlagHacked)
+}
+
+// titleLanguages returns the lowercase codes of the languages the title may be in. No-Intro only
+// tags the languages of releases in several languages, so the languages of the regions are used
+// when there are no language tags.
+func (t Tags) titleLanguages() []string {
+	languages := []string{}
+	for _, language := range t.Languages {
+		languages = append(languages, strings.ToLower(language))
+	}
+	if len(languages) > 0 {
+		return languages
+	}
+	for _, region := range t.Regions {
+		if language, ok := regionLanguages[region]; ok {
+			languages = append(languages, language)
+		}
+	}
+	return uniqueItems(languages)
+}
+
+// parseGroup records the information from a single parenthesised tag
+func (t *Tags) parseGroup(value string) {
+	if match := revisionPattern.FindStringSubmatch(value); match != nil {
+		t.Revision = match[1]
+		return
+	}
+	if match := versionPattern.FindStringSubmatch(value); match != nil {
+		t.Version = match[1]
+		return
+	}
+	if regions := parseRegions(value); len(regions) > 0 {
+		t.Regions = uniqueItems(append(t.Regions, regions...))
+		return
+	}
+	if languages := parseLanguages(value); len(languages) > 0 {
+		t.Languages = uniqueItems(append(t.Languages, languages...))
+	}
+}
+
+// parseRegions returns the regions in a tag, or nothing if the tag is not a region tag. Every
+// part of the tag must be a region for it to count.
+func parseRegions(value string) []string {
+	// No-Intro i.e. "USA, Europe"
+	regions := []string{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if !regionNames[part] {
+			regions = nil
+			break
+		}
+		regions = append(regions, part)
+	}
+	if len(regions) > 0 {
+		return regions
+	}
+
+	// GoodTools i.e. "U" or "UK"
+	if names, ok := goodToolsRegions[value]; ok {
+		return names
+	}
+
+	// TOSEC i.e. "US" or "DE-GB", checked before combined GoodTools codes so "US" is not "USA, Spain"
+	regions = []string{}
+	for _, part := range strings.Split(value, "-") {
+		region, ok := tosecRegions[part]
+		if !ok {
+			regions = nil
+			break
+		}
+		regions = append(regions, region)
+	}
+	if len(regions) > 0 {
+		return regions
+	}
+
+	// combined GoodTools codes i.e. "JUE"
+	regions = []string{}
+	if goodToolsRegionPattern.MatchString(value) {
+		for _, code := range value {
+			regions = append(regions, goodToolsRegions[string(code)]...)
+		}
+	}
+	return regions
+}
+
+// parseLanguages returns the languages in a tag, or nothing if the tag is not a language tag.
+// Languages are returned in the No-Intro format i.e. "En".
+func parseLanguages(value string) []string {
+	languages := []string{}
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '-' || r == '+' }) {
+		part = strings.TrimSpace(part)
+		// No-Intro uses "En" and TOSEC uses "en", anything else is not a language
+		if len(part) != 2 || !unicode.IsLower(rune(part[1])) || !languageCodes[strings.ToLower(part)] {
+			return []string{}
+		}
+		languages = append(languages, strings.ToUpper(part[:1])+part[1:])
+	}
+	return languages
+}
+
+// dumpFlag returns the flag code for a bracketed tag i.e. "b1" becomes "b" and "T+Eng" becomes "T"
+func dumpFlag(value string) string {
+	if strings.HasPrefix(value, "!") {
+		return DumpFlagVerified
+	}
+	if strings.HasPrefix(value, "T+") || strings.HasPrefix(value, "T-") {
+		return "T"
+	}
+	end := strings.IndexFunc(value, func(r rune) bool { return !unicode.IsLower(r) })
+	if end < 0 {
+		end = len(value)
+	}
+	return value[:end]
+}
+
+// tagGroup is a single tag and the bracket it was wrapped in
+type tagGroup struct {
+	bracket rune
+	value   string
+}
+
+// tagGroups returns every parenthesised and bracketed tag in the given string
+func tagGroups(s string) []tagGroup {
+	groups := []tagGroup{}
+	closing := map[rune]rune{'(': ')', '[': ']'}
+	for i, r := range s {
+		end, ok := closing[r]
+		if !ok {
+			continue
+		}
+		if j := strings.IndexRune(s[i+1:], end); j >= 0 {
+			groups = append(groups, tagGroup{bracket: r, value: strings.TrimSpace(s[i+1 : i+1+j])})
+		}
+	}
+	return groups
+}
+
+// trimExtension removes the file extension from a file name. Anything after the last "." which
+// does not look like an extension is kept so titles such as "Dr. Mario" are not cut short.
+func trimExtension(fileName string) string {
+	extension := filepath.Ext(fileName)
+	if len(extension) < 2 || len(extension) > 10 || strings.ContainsAny(extension, " ()[]") ||
+		!strings.ContainsAny(strings.ToLower(extension), "abcdefghijklmnopqrstuvwxyz") {
+		return fileName
+	}
+	return strings.TrimSuffix(fileName, extension)
+}
+
+// toSet converts a slice into a set for quick lookups
+func toSet(items []string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
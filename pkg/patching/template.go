@@ -0,0 +1,50 @@
+package patching
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// FallbackTemplate is a config template used to generate configs for ROMs which have no matching
+// config. The template may contain the placeholders {rom_name}, {system} and {overlay}.
+type FallbackTemplate struct {
+	// Name is the name of the template file, it is recorded as the source of every generated config
+	Name     string
+	Contents string
+	// Overlay is the path to the generic overlay config for the system. It may contain the {system}
+	// placeholder so the same overlay path can be used for every system.
+	Overlay string
+}
+
+// WithFallbackTemplate makes the patcher generate a config from the given template for every ROM
+// which has no matching config
+func WithFallbackTemplate(template FallbackTemplate) Option {
+	return func(p *Patcher) {
+		p.fallbackTemplate = &template
+	}
+}
+
+// applyFallbackTemplate turns every ROM without a matching config into a template match. The
+// system name is taken from the ROM directory i.e. "roms/n64" is the "n64" system. ROMs whose
+// override config is missing are left alone as the user has already decided which config to use.
+func (p *Patcher) applyFallbackTemplate(matches []*match, romDirPath string) {
+	system := filepath.Base(romDirPath)
+	for _, match := range matches {
+		if match.matchType != MatchTypeNone || match.rom == nil || match.err != nil {
+			continue
+		}
+		match.configFile = &Rom{FileName: p.fallbackTemplate.Name}
+		match.matchType = MatchTypeTemplate
+		match.rendered = []byte(p.fallbackTemplate.render(match.rom, system))
+	}
+}
+
+// render fills in the template's placeholders for the given ROM
+func (t *FallbackTemplate) render(rom *Rom, system string) string {
+	overlay := strings.ReplaceAll(t.Overlay, "{system}", system)
+	return strings.NewReplacer(
+		"{rom_name}", strings.TrimSuffix(rom.FileName, filepath.Ext(rom.FileName)),
+		"{system}", system,
+		"{overlay}", overlay,
+	).Replace(t.Contents)
+}
@@ -0,0 +1,84 @@
+package patching
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/retroarch"
+)
+
+// validateConfigs checks the overlay chain of every config file, separating the valid configs
+// from the broken ones. Broken configs are returned with the reason they are broken.
+func (p *Patcher) validateConfigs(configDirPath string, configFiles []*Rom) (valid []*Rom, broken map[*Rom]string) {
+	valid = []*Rom{}
+	broken = map[*Rom]string{}
+	for _, configFile := range configFiles {
+		if reason := p.validateConfig(configDirPath, configFile.FileName); reason != "" {
+			broken[configFile] = reason
+			continue
+		}
+		valid = append(valid, configFile)
+	}
+	return valid, broken
+}
+
+// validateConfig follows a game config to its overlay config and then on to the overlay images,
+// returning a reason if any part of the chain is missing. Configs without an overlay are valid.
+func (p *Patcher) validateConfig(configDirPath, fileName string) string {
+	config, err := p.readConfig(configDirPath, fileName)
+	if err != nil {
+		return fmt.Sprintf("unable to read config: %s", err.Error())
+	}
+
+	overlayPath, ok := config.Get(retroarch.KeyInputOverlay)
+	if !ok || overlayPath == "" || isRetroArchRelative(overlayPath) {
+		return ""
+	}
+	overlayPath = resolvePath(configDirPath, overlayPath)
+	if !p.fileManager.FileExists(filepath.Dir(overlayPath), filepath.Base(overlayPath)) {
+		return fmt.Sprintf("overlay config not found: %s", overlayPath)
+	}
+
+	overlay, err := p.readConfig(filepath.Dir(overlayPath), filepath.Base(overlayPath))
+	if err != nil {
+		return fmt.Sprintf("unable to read overlay config %s: %s", overlayPath, err.Error())
+	}
+	for _, imagePath := range overlay.OverlayImages() {
+		if isRetroArchRelative(imagePath) {
+			continue
+		}
+		// overlay images are relative to the overlay config rather than the game config
+		imagePath = resolvePath(filepath.Dir(overlayPath), imagePath)
+		if !p.fileManager.FileExists(filepath.Dir(imagePath), filepath.Base(imagePath)) {
+			return fmt.Sprintf("overlay image not found: %s", imagePath)
+		}
+	}
+
+	return ""
+}
+
+// readConfig reads and parses a RetroArch config file
+func (p *Patcher) readConfig(directoryPath, fileName string) (retroarch.Config, error) {
+	contents, err := p.fileManager.ReadFile(directoryPath, fileName)
+	if err != nil {
+		return nil, err
+	}
+	return retroarch.ParseConfig(bytes.NewReader(contents))
+}
+
+// resolvePath returns the given path as is if it is absolute, otherwise it is joined to the
+// given base directory
+func resolvePath(baseDirPath, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDirPath, path)
+}
+
+// isRetroArchRelative returns true if the path is relative to the RetroArch directory (":/...").
+// The RetroArch directory is not known so these paths cannot be validated.
+func isRetroArchRelative(path string) bool {
+	return strings.HasPrefix(path, ":")
+}
@@ -0,0 +1,45 @@
+package patching
+
+import "sync"
+
+// WithJobs sets how many ROMs are scanned at the same time. Scanning is mostly waiting on the disk
+// when ROMs are hashed or archives are inspected so large collections are scanned much faster with
+// several jobs. The results are always the same as scanning one ROM at a time.
+func WithJobs(jobs int) Option {
+	return func(p *Patcher) {
+		p.jobs = jobs
+	}
+}
+
+// forEach calls fn for every index from 0 to n using a bounded pool of workers. fn must only write
+// to the results for the index it is given so the order of the results never depends on which
+// worker finished first.
+func (p *Patcher) forEach(n int, fn func(i int)) {
+	jobs := p.jobs
+	if jobs > n {
+		jobs = n
+	}
+	if jobs <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,41 @@
+package patching
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/files"
+)
+
+// BenchmarkScan scans and hashes a large collection of real files with a varying number of jobs
+func BenchmarkScan(b *testing.B) {
+	romDirectoryPath := b.TempDir()
+
+	datGames := []string{}
+	contents := make([]byte, 16*1024)
+	for i := 0; i < 2000; i++ {
+		romFile := fmt.Sprintf("rom%04d.n64", i)
+		copy(contents, romFile)
+		require.NoError(b, os.WriteFile(filepath.Join(romDirectoryPath, romFile), contents, 0644))
+		datGames = append(datGames, fmt.Sprintf(`<game name="Game %04d (USA)"><rom name="Game %04d (USA).n64" crc="%08x"/></game>`, i, i, crc32.ChecksumIEEE(contents)))
+	}
+	dat, err := ParseDat([]byte("<datafile>" + strings.Join(datGames, "\n") + "</datafile>"))
+	require.NoError(b, err)
+
+	for _, jobs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			patcher := NewPatcher(&files.FileManager{}, false, WithDat(dat), WithRomHashing(), WithJobs(jobs))
+			for i := 0; i < b.N; i++ {
+				roms, _, err := patcher.scanRomDirectory(romDirectoryPath)
+				require.NoError(b, err)
+				require.Equal(b, 2000, patcher.resolveCanonicalNames(romDirectoryPath, roms))
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package retroarch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KeyInputOverlay is the key a game config uses to reference its overlay config
+	KeyInputOverlay = "input_overlay"
+	// KeyOverlayCount is the key an overlay config uses to declare how many overlays it has
+	KeyOverlayCount = "overlays"
+)
+
+// Config holds the key/value pairs from a RetroArch config file
+type Config map[string]string
+
+// ParseConfig reads a RetroArch config file. Blank lines, comments and #include directives
+// are ignored. Values may optionally be wrapped in double quotes.
+func ParseConfig(r io.Reader) (Config, error) {
+	config := Config{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key := strings.TrimSpace(line[:i])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", lineNumber)
+		}
+		config[key] = parseValue(line[i+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Get returns the value for the given key and whether it was set
+func (c Config) Get(key string) (string, bool) {
+	value, ok := c[key]
+	return value, ok
+}
+
+// OverlayImages returns the image paths referenced by an overlay config, in overlay order
+func (c Config) OverlayImages() []string {
+	count, err := strconv.Atoi(c[KeyOverlayCount])
+	if err != nil {
+		return []string{}
+	}
+	images := []string{}
+	for i := 0; i < count; i++ {
+		if image, ok := c.Get(fmt.Sprintf("overlay%d_overlay", i)); ok && image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// parseValue trims a raw value and removes any surrounding quotes or trailing comments
+func parseValue(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "\"") {
+		if i := strings.Index(value[1:], "\""); i >= 0 {
+			return value[1 : i+1]
+		}
+		return value[1:]
+	}
+	if i := strings.Index(value, "#"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
@@ -0,0 +1,72 @@
+package retroarch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	tt := map[string]struct {
+		contents       string
+		expectedConfig Config
+	}{
+		"game config": {
+			contents: "input_overlay = \"/opt/retropie/configs/all/retroarch/overlay/GameBezels/N64/AeroFighters Assault (USA).cfg\"\n" +
+				"input_overlay_opacity = \"1.000000\"\n",
+			expectedConfig: Config{
+				"input_overlay":         "/opt/retropie/configs/all/retroarch/overlay/GameBezels/N64/AeroFighters Assault (USA).cfg",
+				"input_overlay_opacity": "1.000000",
+			},
+		},
+		"overlay config": {
+			contents: "overlays = 1\n" +
+				"overlay0_overlay = \"AeroFighters Assault (USA).png\"\n" +
+				"overlay0_full_screen = true\n",
+			expectedConfig: Config{
+				"overlays":             "1",
+				"overlay0_overlay":     "AeroFighters Assault (USA).png",
+				"overlay0_full_screen": "true",
+			},
+		},
+		"comments and blank lines": {
+			contents: "# generated by the bezel project\n\n" +
+				"#include \"/opt/retropie/configs/all/retroarch.cfg\"\n" +
+				"aspect_ratio_index = 22 # custom\n",
+			expectedConfig: Config{
+				"aspect_ratio_index": "22",
+			},
+		},
+		"windows line endings": {
+			contents: "input_overlay = \"overlay.cfg\"\r\ninput_overlay_enable = true\r\n",
+			expectedConfig: Config{
+				"input_overlay":        "overlay.cfg",
+				"input_overlay_enable": "true",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			config, err := ParseConfig(strings.NewReader(tc.contents))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedConfig, config)
+		})
+	}
+}
+
+func TestParseInvalidConfig(t *testing.T) {
+	_, err := ParseConfig(strings.NewReader("input_overlay\n"))
+	assert.Error(t, err)
+}
+
+func TestOverlayImages(t *testing.T) {
+	config := Config{
+		"overlays":         "2",
+		"overlay0_overlay": "bezel.png",
+		"overlay1_overlay": "bezel-alt.png",
+	}
+	assert.Equal(t, []string{"bezel.png", "bezel-alt.png"}, config.OverlayImages())
+}
@@ -0,0 +1,61 @@
+package review
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+// TerminalReviewer asks the user about each match in the terminal
+type TerminalReviewer struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// NewTerminalReviewer returns a reviewer which reads answers from in and writes questions to out
+func NewTerminalReviewer(in io.Reader, out io.Writer) *TerminalReviewer {
+	return &TerminalReviewer{
+		in:  bufio.NewScanner(in),
+		out: out,
+	}
+}
+
+// Review lists the candidates for the ROM and asks the user what to do until they give a valid answer
+func (r *TerminalReviewer) Review(item patching.ReviewItem) (patching.Decision, error) {
+	fmt.Fprintf(r.out, "\n[%d/%d] %s -> %s\n", item.Index, item.Total, item.Rom, item.NewFile)
+	for i, candidate := range item.Candidates {
+		line := fmt.Sprintf("  %d) %s (%s", i+1, candidate.ConfigFile, candidate.MatchType)
+		if candidate.MatchType == patching.MatchTypeScored {
+			line += fmt.Sprintf(", score: %.2f", candidate.Score)
+		}
+		fmt.Fprintln(r.out, line+")")
+	}
+
+	for {
+		fmt.Fprint(r.out, "[a]ccept 1, [r]eject, [s]kip or pick a candidate by number: ")
+		if !r.in.Scan() {
+			if err := r.in.Err(); err != nil {
+				return patching.Decision{}, err
+			}
+			return patching.Decision{}, io.ErrUnexpectedEOF
+		}
+
+		answer := strings.ToLower(strings.TrimSpace(r.in.Text()))
+		switch answer {
+		case "a", "accept", "y", "yes":
+			return patching.Decision{Action: patching.DecisionAccept, Config: item.Candidates[0].ConfigFile}, nil
+		case "r", "reject", "n", "no":
+			return patching.Decision{Action: patching.DecisionReject, Config: item.Candidates[0].ConfigFile}, nil
+		case "s", "skip", "":
+			return patching.Decision{Action: patching.DecisionSkip}, nil
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(item.Candidates) {
+			return patching.Decision{Action: patching.DecisionAccept, Config: item.Candidates[i-1].ConfigFile}, nil
+		}
+		fmt.Fprintf(r.out, "unknown answer: %s\n", answer)
+	}
+}
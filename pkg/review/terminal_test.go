@@ -0,0 +1,68 @@
+package review
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestTerminalReviewer(t *testing.T) {
+	item := patching.ReviewItem{
+		Rom:     "Wave Race 64 (E).n64",
+		NewFile: "Wave Race 64 (E).cfg",
+		Candidates: []patching.ReviewCandidate{
+			{ConfigFile: "Wave Race 64 (USA).cfg", MatchType: patching.MatchTypeExact},
+			{ConfigFile: "Wave Race 64 (Japan).cfg", MatchType: patching.MatchTypeExact},
+		},
+		Index: 1,
+		Total: 1,
+	}
+
+	tt := map[string]struct {
+		input            string
+		expectedDecision patching.Decision
+	}{
+		"accept": {
+			input:            "a\n",
+			expectedDecision: patching.Decision{Action: patching.DecisionAccept, Config: "Wave Race 64 (USA).cfg"},
+		},
+		"reject": {
+			input:            "r\n",
+			expectedDecision: patching.Decision{Action: patching.DecisionReject, Config: "Wave Race 64 (USA).cfg"},
+		},
+		"skip": {
+			input:            "\n",
+			expectedDecision: patching.Decision{Action: patching.DecisionSkip},
+		},
+		"pick a different candidate": {
+			input:            "2\n",
+			expectedDecision: patching.Decision{Action: patching.DecisionAccept, Config: "Wave Race 64 (Japan).cfg"},
+		},
+		"asks again after an unknown answer": {
+			input:            "3\nmaybe\nr\n",
+			expectedDecision: patching.Decision{Action: patching.DecisionReject, Config: "Wave Race 64 (USA).cfg"},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			decision, err := NewTerminalReviewer(strings.NewReader(tc.input), out).Review(item)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDecision, decision)
+			assert.Contains(t, out.String(), "2) Wave Race 64 (Japan).cfg (exact)")
+		})
+	}
+}
+
+func TestTerminalReviewerWithoutInput(t *testing.T) {
+	_, err := NewTerminalReviewer(strings.NewReader(""), io.Discard).Review(patching.ReviewItem{})
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
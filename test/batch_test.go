@@ -0,0 +1,122 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/batch"
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+const snesRomDirectoryPath = "C:\\Games\\SNES"
+
+func TestBatchPatchesEverySystem(t *testing.T) {
+	// logs and manifests are written to disk so the config directories must really exist
+	bezelDirectoryPath := t.TempDir()
+	snesBezelDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+	manager.SetDirectoryContents(snesRomDirectoryPath, []string{"Super Mario World (U) [!].smc", "Zelda.smc"})
+	manager.SetDirectoryContents(snesBezelDirectoryPath, []string{"Super Mario World (USA).cfg"})
+
+	systems := []batch.System{
+		{Name: "n64", ConfigDirectory: bezelDirectoryPath, RomDirectory: romDirectoryPath, Match: "exact"},
+		{Name: "snes", ConfigDirectory: snesBezelDirectoryPath, RomDirectory: snesRomDirectoryPath},
+		{Name: "psx", ConfigDirectory: "C:\\Retroarch\\config\\PCSX", RomDirectory: "C:\\Games\\PSX"},
+	}
+
+	results := batch.Run(patching.NewPatcher(manager, true), systems)
+	require.Len(t, results, 3)
+
+	// the n64 system only allows exact matches so "The" matches are skipped
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, 0, results[0].PatchResult.CreatedFiles)
+	assert.Equal(t, 1, results[0].PatchResult.SkippedFiles)
+	n64Contents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg"}, n64Contents)
+
+	require.NoError(t, results[1].Err)
+	assert.Equal(t, 1, results[1].PatchResult.CreatedFiles)
+	assert.Equal(t, 1, results[1].PatchResult.MissingConfigs)
+	snesContents, err := manager.GetDirectoryContents(snesBezelDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Super Mario World (USA).cfg", "Super Mario World (U) [!].cfg"}, snesContents)
+
+	// the missing system should fail without stopping the others
+	assert.Error(t, results[2].Err)
+	assert.True(t, batch.Failed(results))
+
+	summary := batch.Summary(results, true)
+	assert.Contains(t, summary, "Patched 2 of 3 systems")
+	assert.Contains(t, summary, "Created 1 new files")
+	assert.Contains(t, summary, "psx: directory does not exist")
+}
+
+func TestBatchExtensionsReplaceDefaultExclusions(t *testing.T) {
+	// logs are written to disk so the config directory must really exist
+	bezelDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Goldeneye 007 (U).n64", "Goldeneye 007 (U).srm", "notes.txt"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"Goldeneye 007 (USA).cfg"})
+
+	tt := map[string]struct {
+		extensions      []string
+		expectedRoms    int
+		expectedIgnored int
+	}{
+		"default exclusions apply without a list of extensions": {
+			expectedRoms:    1,
+			expectedIgnored: 2,
+		},
+		"listed extensions are used even if they are excluded by default": {
+			extensions:      []string{"n64", "srm"},
+			expectedRoms:    2,
+			expectedIgnored: 1,
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			systems := []batch.System{{Name: "n64", ConfigDirectory: bezelDirectoryPath, RomDirectory: romDirectoryPath, Extensions: tc.extensions}}
+			results := batch.Run(patching.NewPatcher(manager, false), systems)
+			require.Len(t, results, 1)
+			require.NoError(t, results[0].Err)
+			assert.Equal(t, tc.expectedRoms, results[0].PatchResult.RomCount)
+			assert.Equal(t, tc.expectedIgnored, results[0].PatchResult.IgnoredFiles)
+		})
+	}
+}
+
+func TestBatchMatchLevelsUseThePatchersMatchers(t *testing.T) {
+	// logs are written to disk so the config directory must really exist
+	bezelDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"SM64 (U).n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"Super Mario 64 (USA).cfg"})
+
+	matchers := append([]patching.Matcher{patching.ExactMatcher{}, abbreviationMatcher{"sm64": "super mario 64"}}, patching.DefaultMatchers()[2:]...)
+	systems := []batch.System{
+		{Name: "n64", ConfigDirectory: bezelDirectoryPath, RomDirectory: romDirectoryPath, Match: "abbreviation"},
+		{Name: "n64-typo", ConfigDirectory: bezelDirectoryPath, RomDirectory: romDirectoryPath, Match: "close-enough"},
+	}
+
+	results := batch.Run(patching.NewPatcher(manager, false, patching.WithMatchers(matchers...)), systems)
+	require.Len(t, results, 2)
+
+	// the custom matcher's name selects its match level
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, 1, results[0].PatchResult.CreatedFiles)
+
+	// a level which is not one of the matchers only fails its own system
+	assert.EqualError(t, results[1].Err, "unknown match type: close-enough")
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestDatMatching(t *testing.T) {
+	renamedRomContents := []byte("conker")
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{
+		"SM64.z64",
+		"cbfd.n64",
+		"Goldeneye 007 (U).n64",
+		"Unknown Homebrew.n64",
+	})
+	manager.SetFileContents(romDirectoryPath, "cbfd.n64", renamedRomContents)
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{
+		"Super Mario 64 (USA).cfg",
+		"Conker's Bad Fur Day (USA).cfg",
+		"Goldeneye 007 (USA).cfg",
+	})
+
+	// the DAT knows the renamed ROMs by their original file name and by their checksum
+	dat, err := patching.ParseDat([]byte(fmt.Sprintf(`<datafile>
+	<game name="Super Mario 64 (USA)"><rom name="SM64.z64" crc="635a2bff"/></game>
+	<game name="Conker's Bad Fur Day (USA)"><rom name="Conker's Bad Fur Day (USA).z64" crc="%08x"/></game>
+	<game name="GoldenEye 007 (USA)"><rom name="GoldenEye 007 (USA).z64" crc="dcbc50d1"/></game>
+</datafile>`, crc32.ChecksumIEEE(renamedRomContents))))
+	require.NoError(t, err)
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true, patching.WithDat(dat), patching.WithRomHashing())
+	result, _ := patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NotNil(t, result)
+
+	// DAT matches come between exact and alternate matches so they are included at the alternate level
+	created := map[string]patching.ReportEntry{}
+	for _, entry := range result.Created {
+		created[entry.RomFile] = entry
+	}
+	assert.Len(t, created, 3)
+	assert.Equal(t, patching.MatchTypeDat, created["SM64.z64"].MatchType)
+	assert.Equal(t, "Super Mario 64 (USA).cfg", created["SM64.z64"].ConfigFile)
+	assert.Equal(t, "Super Mario 64 (USA)", created["SM64.z64"].CanonicalName)
+	assert.Equal(t, patching.MatchTypeDat, created["cbfd.n64"].MatchType)
+	assert.Equal(t, "Conker's Bad Fur Day (USA).cfg", created["cbfd.n64"].ConfigFile)
+
+	// correctly named ROMs are matched as normal
+	assert.Equal(t, patching.MatchTypeExact, created["Goldeneye 007 (U).n64"].MatchType)
+	assert.Empty(t, created["Goldeneye 007 (U).n64"].CanonicalName)
+
+	assert.Equal(t, []string{"Unknown Homebrew.n64"}, result.UnmatchedRoms)
+	assert.True(t, manager.FileExists(bezelDirectoryPath, "SM64.cfg"))
+	assert.True(t, manager.FileExists(bezelDirectoryPath, "cbfd.cfg"))
+}
+
+func TestDatMatchesAreSkippedByExactMatching(t *testing.T) {
+	// logs are written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"SM64.z64", "Goldeneye 007 (U).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Super Mario 64 (USA).cfg", "Goldeneye 007 (USA).cfg"})
+
+	dat, err := patching.ParseDat([]byte(`<datafile>
+	<game name="Super Mario 64 (USA)"><rom name="SM64.z64" crc="635a2bff"/></game>
+</datafile>`))
+	require.NoError(t, err)
+
+	patcher := patching.NewPatcher(manager, true, patching.WithDat(dat))
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeExact)
+	require.NoError(t, err)
+
+	// only the exact match is created
+	require.Len(t, result.Created, 1)
+	assert.Equal(t, "Goldeneye 007 (U).n64", result.Created[0].RomFile)
+	require.Len(t, result.Skipped, 1)
+	assert.Equal(t, "SM64.z64", result.Skipped[0].RomFile)
+	assert.Equal(t, patching.MatchTypeDat, result.Skipped[0].MatchType)
+
+	log, err := os.ReadFile(result.LogPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "NEW FILES (DAT MATCHES) [SKIPPED]\nSM64.z64 -> SM64.cfg")
+}
+
+func TestDatMatchingWithoutHashing(t *testing.T) {
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"cbfd.n64"})
+	manager.SetFileContents(romDirectoryPath, "cbfd.n64", []byte("conker"))
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"Conker's Bad Fur Day (USA).cfg"})
+
+	dat, err := patching.ParseDat([]byte(fmt.Sprintf(`<datafile>
+	<game name="Conker's Bad Fur Day (USA)"><rom name="Conker's Bad Fur Day (USA).z64" crc="%08x"/></game>
+</datafile>`, crc32.ChecksumIEEE([]byte("conker")))))
+	require.NoError(t, err)
+
+	// the ROM is only known by its checksum so it cannot be resolved without hashing
+	patcher := patching.NewPatcher(manager, true, patching.WithDat(dat))
+	result, _ := patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NotNil(t, result)
+	assert.Empty(t, result.Created)
+	assert.Equal(t, []string{"cbfd.n64"}, result.UnmatchedRoms)
+}
+
+func TestZippedDatMatching(t *testing.T) {
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"cbfd.zip"})
+	manager.SetArchiveChecksums(romDirectoryPath, "cbfd.zip", []string{"0badc0de", "1a2b3c4d"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"Conker's Bad Fur Day (USA).cfg"})
+
+	dat, err := patching.ParseDat([]byte(`<datafile>
+	<game name="Conker's Bad Fur Day (USA)"><rom name="Conker's Bad Fur Day (USA).z64" crc="1A2B3C4D"/></game>
+</datafile>`))
+	require.NoError(t, err)
+
+	// zipped ROMs are resolved by the checksums of the files inside the archive
+	patcher := patching.NewPatcher(manager, true, patching.WithDat(dat), patching.WithRomHashing())
+	result, _ := patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NotNil(t, result)
+	require.Len(t, result.Created, 1)
+	assert.Equal(t, patching.MatchTypeDat, result.Created[0].MatchType)
+	assert.Equal(t, "Conker's Bad Fur Day (USA).cfg", result.Created[0].ConfigFile)
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/batch"
+	"github.com/wamphlett/bezel-project-patcher/pkg/emulationstation"
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+const configRootPath = "/opt/retropie/configs/all/retroarch/config"
+
+func TestDiscoverSystemsWithBezelConfigs(t *testing.T) {
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents("/roms/n64", []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents("/roms/megadrive", []string{"Sonic The Hedgehog (USA, Europe).md"})
+	manager.SetDirectoryContents("/roms/snes", []string{"Super Mario World (USA).sfc"})
+	manager.SetDirectoryContents(filepath.Join(configRootPath, "Mupen64Plus GLES2"), []string{"New Tetris, The (USA).cfg"})
+	manager.SetDirectoryContents(filepath.Join(configRootPath, "Genesis Plus GX"), []string{"Sonic The Hedgehog (USA, Europe).cfg"})
+	manager.SetDirectoryContents(filepath.Join(configRootPath, "PicoDrive"), []string{"Sonic The Hedgehog (USA, Europe).cfg"})
+	// snes has a config directory but no bezel configs have been installed
+	manager.SetDirectoryContents(filepath.Join(configRootPath, "Snes9x"), []string{"Snes9x.opt"})
+
+	esSystems := []emulationstation.System{
+		{Name: "n64", Path: "/roms/n64", Extensions: []string{".n64", ".z64"}},
+		{Name: "megadrive", Path: "/roms/megadrive", Extensions: []string{".md"}},
+		{Name: "snes", Path: "/roms/snes", Extensions: []string{".sfc"}},
+		{Name: "psx", Path: "/roms/psx", Extensions: []string{".cue"}},
+	}
+
+	systems := batch.Discover(manager, esSystems, configRootPath, string(patching.MatchTypeFuzzy))
+	assert.Equal(t, []batch.System{
+		{
+			Name:            "n64",
+			ConfigDirectory: filepath.Join(configRootPath, "Mupen64Plus GLES2"),
+			RomDirectory:    "/roms/n64",
+			Match:           "fuzzy",
+			Extensions:      []string{".n64", ".z64"},
+		},
+		{
+			Name:            "megadrive (Genesis Plus GX)",
+			ConfigDirectory: filepath.Join(configRootPath, "Genesis Plus GX"),
+			RomDirectory:    "/roms/megadrive",
+			Match:           "fuzzy",
+			Extensions:      []string{".md"},
+		},
+		{
+			Name:            "megadrive (PicoDrive)",
+			ConfigDirectory: filepath.Join(configRootPath, "PicoDrive"),
+			RomDirectory:    "/roms/megadrive",
+			Match:           "fuzzy",
+			Extensions:      []string{".md"},
+		},
+	}, systems)
+}
+
+func TestSystemExtensionsFilterRoms(t *testing.T) {
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "The New Tetris (USA).srm", "The New Tetris (U).txt"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true, patching.WithIncludedExtensions("n64", ".Z64"))
+	patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+	// only the n64 file should have produced a config
+	actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "The New Tetris (USA).cfg"}, actualContents)
+}
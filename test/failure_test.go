@@ -0,0 +1,152 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestFailedFilesAreReported(t *testing.T) {
+	tt := map[string]struct {
+		opts                     []patching.Option
+		expectedBezelDirContents []string
+		expectedSkippedFiles     int
+	}{
+		"remaining files are still created": {
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"AeroFighters Assault (USA).cfg",
+				"aerofighters assault (U) [!].cfg",
+			},
+		},
+		"fail fast stops at the first failure": {
+			opts: []patching.Option{patching.WithFailFast()},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"AeroFighters Assault (USA).cfg",
+			},
+			expectedSkippedFiles: 1,
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// the manifest is written to disk so the config directory must really exist
+			configDirectoryPath := t.TempDir()
+
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "aerofighters assault (U) [!].n64"})
+			manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg"})
+			manager.SetCreateFailure(configDirectoryPath, "The New Tetris (USA).cfg", errors.New("read-only file system"))
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, tc.opts...)
+			result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+			assert.ErrorIs(t, err, patching.ErrFilesFailed)
+			require.NotNil(t, result)
+
+			require.Len(t, result.Failed, 1)
+			assert.Equal(t, "The New Tetris (USA).cfg", result.Failed[0].NewFile)
+			assert.Equal(t, "read-only file system", result.Failed[0].Reason)
+			assert.Equal(t, 1, result.FailedFiles)
+			assert.Equal(t, tc.expectedSkippedFiles, result.SkippedFiles)
+
+			// check the contents of the config directory to ensure the expected config files exist
+			actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedBezelDirContents, actualContents)
+		})
+	}
+}
+
+func TestFailedTemplatesAreReported(t *testing.T) {
+	tt := map[string]struct {
+		opts                 []patching.Option
+		expectedCreatedFiles []string
+		expectedSkippedFiles int
+	}{
+		"remaining templates are still generated": {
+			expectedCreatedFiles: []string{"Tetrisphere (U).cfg"},
+		},
+		"fail fast stops at the first failure": {
+			opts:                 []patching.Option{patching.WithFailFast()},
+			expectedSkippedFiles: 1,
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// the manifest is written to disk so the config directory must really exist
+			configDirectoryPath := t.TempDir()
+
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"Doom 64 (U).n64", "Tetrisphere (U).n64"})
+			manager.SetDirectoryContents(configDirectoryPath, []string{"fallback.cfg"})
+			manager.SetCreateFailure(configDirectoryPath, "Doom 64 (U).cfg", errors.New("read-only file system"))
+
+			// run the patcher
+			opts := append([]patching.Option{patching.WithFallbackTemplate(patching.FallbackTemplate{Name: "fallback.cfg", Contents: "# {rom_name}"})}, tc.opts...)
+			patcher := patching.NewPatcher(manager, true, opts...)
+			result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+			assert.ErrorIs(t, err, patching.ErrFilesFailed)
+			require.NotNil(t, result)
+
+			require.Len(t, result.Failed, 1)
+			assert.Equal(t, "Doom 64 (U).cfg", result.Failed[0].NewFile)
+			assert.Equal(t, patching.MatchTypeTemplate, result.Failed[0].MatchType)
+			assert.Equal(t, "read-only file system", result.Failed[0].Reason)
+			assert.Equal(t, tc.expectedSkippedFiles, result.SkippedFiles)
+			assert.False(t, manager.FileExists(configDirectoryPath, "Doom 64 (U).cfg"))
+
+			// only the generated files are recorded so a rollback never touches the failed file
+			manifestFiles := []string{}
+			if manifest, err := patching.LoadManifest(configDirectoryPath, ""); err == nil {
+				for _, entry := range manifest.Files {
+					manifestFiles = append(manifestFiles, entry.File)
+				}
+			}
+			assert.ElementsMatch(t, tc.expectedCreatedFiles, manifestFiles)
+		})
+	}
+}
+
+func TestFailedFilesSharingAConfigNameAreNotExisting(t *testing.T) {
+	// the manifest is written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// both dumps of the game use the same config name
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Wave Race 64 (E).n64", "Wave Race 64 (E).z64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Wave Race 64 (Europe).cfg"})
+	manager.SetCreateFailure(configDirectoryPath, "Wave Race 64 (E).cfg", errors.New("read-only file system"))
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true)
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	assert.ErrorIs(t, err, patching.ErrFilesFailed)
+	require.NotNil(t, result)
+
+	// the file was never created so neither ROM has an existing config
+	assert.Empty(t, result.Existing)
+	require.Len(t, result.Failed, 2)
+	for _, entry := range result.Failed {
+		assert.Equal(t, "Wave Race 64 (E).cfg", entry.NewFile)
+		assert.Equal(t, "read-only file system", entry.Reason)
+	}
+	assert.Empty(t, result.Plan.Operations)
+
+	// failing fast skips the second ROM rather than treating its config as existing
+	patcher = patching.NewPatcher(manager, true, patching.WithFailFast())
+	result, err = patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	assert.ErrorIs(t, err, patching.ErrFilesFailed)
+	require.NotNil(t, result)
+	assert.Empty(t, result.Existing)
+	assert.Len(t, result.Failed, 1)
+	assert.Len(t, result.Skipped, 1)
+}
@@ -1,15 +1,44 @@
 package test
 
-import "errors"
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"path/filepath"
+)
 
 type stubFileManager struct {
 	directories map[string][]string
+	contents    map[string][]byte
+	archives    map[string][]string
+	checksums   map[string][]string
+	// links maps the path of a linked file to the path of the file it is linked to
+	links map[string]stubLink
+	// failures maps the path of a file to the error returned when it is created
+	failures map[string]error
+	// removeFailures maps the path of a file to the error returned when it is removed or moved
+	removeFailures map[string]error
+}
+
+// stubLink is a symbolic or hard link in the mock file system
+type stubLink struct {
+	target   string
+	symbolic bool
 }
 
 // NewStubFileManager creates a mock in-memory file system
 func NewStubFileManager() *stubFileManager {
 	return &stubFileManager{
 		directories: map[string][]string{},
+		contents:    map[string][]byte{},
+		archives:    map[string][]string{},
+		checksums:   map[string][]string{},
+		links:       map[string]stubLink{},
+		failures:    map[string]error{},
+
+		removeFailures: map[string]error{},
 	}
 }
 
@@ -21,7 +50,83 @@ func (m *stubFileManager) GetDirectoryContents(directoryPath string) ([]string,
 	return contents, nil
 }
 
+func (m *stubFileManager) GetDirectoryContentsRecursive(directoryPath string) ([]string, error) {
+	contents, err := m.GetDirectoryContents(directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	filePaths := []string{}
+	for _, item := range contents {
+		// any entry which is also a mocked directory is treated as a subdirectory
+		if _, ok := m.directories[filepath.Join(directoryPath, item)]; !ok {
+			filePaths = append(filePaths, item)
+			continue
+		}
+		nestedPaths, err := m.GetDirectoryContentsRecursive(filepath.Join(directoryPath, item))
+		if err != nil {
+			return nil, err
+		}
+		for _, nestedPath := range nestedPaths {
+			filePaths = append(filePaths, filepath.Join(item, nestedPath))
+		}
+	}
+	return filePaths, nil
+}
+
+func (m *stubFileManager) GetArchiveContents(directoryPath, fileName string) ([]string, error) {
+	contents, ok := m.archives[filepath.Join(directoryPath, fileName)]
+	if !ok {
+		return nil, errors.New("not a valid archive")
+	}
+	return contents, nil
+}
+
+func (m *stubFileManager) GetArchiveChecksums(directoryPath, fileName string) ([]string, error) {
+	checksums, ok := m.checksums[filepath.Join(directoryPath, fileName)]
+	if !ok {
+		return nil, errors.New("not a valid archive")
+	}
+	return checksums, nil
+}
+
 func (m *stubFileManager) CopyFileWithName(directoryPath, fileName, newName string) error {
+	if err, ok := m.failures[filepath.Join(directoryPath, newName)]; ok {
+		return err
+	}
+	contents, err := m.GetDirectoryContents(directoryPath)
+	if err != nil {
+		return err
+	}
+	if !inSlice(fileName, contents) {
+		return errors.New("file does not exist")
+	}
+
+	m.directories[directoryPath] = append(m.directories[directoryPath], newName)
+	m.contents[filepath.Join(directoryPath, newName)] = m.contents[filepath.Join(directoryPath, fileName)]
+
+	return nil
+}
+
+func (m *stubFileManager) WriteFile(directoryPath, fileName string, contents []byte) error {
+	if err, ok := m.failures[filepath.Join(directoryPath, fileName)]; ok {
+		return err
+	}
+	if _, ok := m.directories[directoryPath]; !ok {
+		return errors.New("directory does not exist")
+	}
+
+	if !inSlice(fileName, m.directories[directoryPath]) {
+		m.directories[directoryPath] = append(m.directories[directoryPath], fileName)
+	}
+	m.contents[filepath.Join(directoryPath, fileName)] = contents
+
+	return nil
+}
+
+func (m *stubFileManager) LinkFileWithName(directoryPath, fileName, newName string, symbolic bool) error {
+	if err, ok := m.failures[filepath.Join(directoryPath, newName)]; ok {
+		return err
+	}
 	contents, err := m.GetDirectoryContents(directoryPath)
 	if err != nil {
 		return err
@@ -31,11 +136,26 @@ func (m *stubFileManager) CopyFileWithName(directoryPath, fileName, newName stri
 	}
 
 	m.directories[directoryPath] = append(m.directories[directoryPath], newName)
+	m.links[filepath.Join(directoryPath, newName)] = stubLink{target: filepath.Join(directoryPath, fileName), symbolic: symbolic}
 
 	return nil
 }
 
 func (m *stubFileManager) FileExists(directoryPath, fileName string) bool {
+	if !m.LinkExists(directoryPath, fileName) {
+		return false
+	}
+	// symbolic links are followed so a link to a missing file does not exist
+	if link, ok := m.links[filepath.Join(directoryPath, fileName)]; ok && link.symbolic {
+		return m.LinkExists(filepath.Dir(link.target), filepath.Base(link.target))
+	}
+	return true
+}
+
+func (m *stubFileManager) LinkExists(directoryPath, fileName string) bool {
+	// nested files are looked up in the directory they belong to
+	directoryPath, fileName = filepath.Split(filepath.Join(directoryPath, fileName))
+	directoryPath = filepath.Clean(directoryPath)
 	files, ok := m.directories[directoryPath]
 	if !ok {
 		return false
@@ -48,10 +168,106 @@ func (m *stubFileManager) FileExists(directoryPath, fileName string) bool {
 	return false
 }
 
+func (m *stubFileManager) IsDirectory(directoryPath, fileName string) bool {
+	_, ok := m.directories[filepath.Join(directoryPath, fileName)]
+	return ok
+}
+
+func (m *stubFileManager) ReadFile(directoryPath, fileName string) ([]byte, error) {
+	// nested files are looked up in the directory they belong to
+	directoryPath, fileName = filepath.Split(filepath.Join(directoryPath, fileName))
+	directoryPath = filepath.Clean(directoryPath)
+	if !m.FileExists(directoryPath, fileName) {
+		return nil, errors.New("file does not exist")
+	}
+	// linked files always have the same contents as the file they are linked to
+	path := filepath.Join(directoryPath, fileName)
+	if link, ok := m.links[path]; ok {
+		path = link.target
+	}
+	return m.contents[path], nil
+}
+
+func (m *stubFileManager) RemoveFile(directoryPath, fileName string) error {
+	if err, ok := m.removeFailures[filepath.Join(directoryPath, fileName)]; ok {
+		return err
+	}
+	if !m.LinkExists(directoryPath, fileName) {
+		return errors.New("file does not exist")
+	}
+	files := []string{}
+	for _, f := range m.directories[directoryPath] {
+		if f != fileName {
+			files = append(files, f)
+		}
+	}
+	m.directories[directoryPath] = files
+	delete(m.contents, filepath.Join(directoryPath, fileName))
+	delete(m.links, filepath.Join(directoryPath, fileName))
+	return nil
+}
+
+func (m *stubFileManager) MoveFile(directoryPath, fileName, newDirectoryPath string) error {
+	if err, ok := m.removeFailures[filepath.Join(directoryPath, fileName)]; ok {
+		return err
+	}
+	if !m.LinkExists(directoryPath, fileName) {
+		return errors.New("file does not exist")
+	}
+
+	path := filepath.Join(directoryPath, fileName)
+	newPath := filepath.Join(newDirectoryPath, fileName)
+	m.directories[newDirectoryPath] = append(m.directories[newDirectoryPath], fileName)
+	m.contents[newPath] = m.contents[path]
+	if link, ok := m.links[path]; ok {
+		m.links[newPath] = link
+	}
+
+	return m.RemoveFile(directoryPath, fileName)
+}
+
+func (m *stubFileManager) HashFile(directoryPath, fileName string) (string, string, error) {
+	contents, err := m.ReadFile(directoryPath, fileName)
+	if err != nil {
+		return "", "", err
+	}
+	sha1Sum := sha1.Sum(contents)
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(contents)), hex.EncodeToString(sha1Sum[:]), nil
+}
+
 func (m *stubFileManager) SetDirectoryContents(directoryPath string, contents []string) {
 	m.directories[directoryPath] = contents
 }
 
+func (m *stubFileManager) SetFileContents(directoryPath, fileName string, contents []byte) {
+	m.contents[filepath.Join(directoryPath, fileName)] = contents
+}
+
+// SetCreateFailure makes any attempt to create the given file fail with the given error
+func (m *stubFileManager) SetCreateFailure(directoryPath, fileName string, err error) {
+	m.failures[filepath.Join(directoryPath, fileName)] = err
+}
+
+// SetRemoveFailure makes any attempt to remove or move the given file fail with the given error
+func (m *stubFileManager) SetRemoveFailure(directoryPath, fileName string, err error) {
+	m.removeFailures[filepath.Join(directoryPath, fileName)] = err
+}
+
+func (m *stubFileManager) SetArchiveContents(directoryPath, fileName string, contents []string) {
+	m.archives[filepath.Join(directoryPath, fileName)] = contents
+}
+
+// SetArchiveChecksums sets the CRC32 checksums of the files inside the given archive
+func (m *stubFileManager) SetArchiveChecksums(directoryPath, fileName string, checksums []string) {
+	m.checksums[filepath.Join(directoryPath, fileName)] = checksums
+}
+
+// Link returns the link for the given file, if the file is a link
+func (m *stubFileManager) Link(directoryPath, fileName string) (stubLink, bool) {
+	link, ok := m.links[filepath.Join(directoryPath, fileName)]
+	return link, ok
+}
+
 func inSlice(s string, slice []string) bool {
 	for _, item := range slice {
 		if s == item {
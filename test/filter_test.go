@@ -0,0 +1,105 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestRomDirectoryFiltering(t *testing.T) {
+	tt := map[string]struct {
+		romDirContents           []string
+		opts                     []patching.Option
+		expectedBezelDirContents []string
+	}{
+		"saves, media and metadata are ignored by default": {
+			romDirContents: []string{
+				"The New Tetris (USA).n64",
+				"New Tetris, The (U).srm",
+				"New Tetris, The (U).state",
+				"New Tetris, The (U).state3",
+				"New Tetris, The (U).txt",
+				"gamelist.xml",
+				".New Tetris, The (U).n64",
+				"media",
+			},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"The New Tetris (USA).cfg",
+			},
+		},
+		"default exclusions can be disabled": {
+			romDirContents: []string{"The New Tetris (USA).n64", "The New Tetris (U).srm"},
+			opts:           []patching.Option{patching.WithoutDefaultExclusions()},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"The New Tetris (USA).cfg",
+				"The New Tetris (U).cfg",
+			},
+		},
+		"excluded extensions": {
+			romDirContents: []string{"The New Tetris (USA).n64", "The New Tetris (U).v64"},
+			opts:           []patching.Option{patching.WithExcludedExtensions("V64")},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"The New Tetris (USA).cfg",
+			},
+		},
+		"included extensions override the default exclusions": {
+			romDirContents: []string{"The New Tetris (USA).n64", "The New Tetris (U).dat"},
+			opts:           []patching.Option{patching.WithIncludedExtensions(".dat")},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"The New Tetris (U).cfg",
+			},
+		},
+		"excluded extensions override included extensions": {
+			romDirContents: []string{"The New Tetris (USA).n64", "The New Tetris (U).v64"},
+			opts: []patching.Option{
+				patching.WithIncludedExtensions(".n64", ".v64"),
+				patching.WithExcludedExtensions(".v64"),
+			},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"The New Tetris (USA).cfg",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, tc.romDirContents)
+			manager.SetDirectoryContents(filepath.Join(romDirectoryPath, "media"), []string{"New Tetris, The (U).png"})
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, tc.opts...)
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+			// check the contents of the bezel directory to ensure the expected config files exist
+			actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectedBezelDirContents, actualContents)
+		})
+	}
+}
+
+func TestIgnoredFilesAreCounted(t *testing.T) {
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "The New Tetris (USA).srm", "gamelist.xml"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	result, _ := patching.NewPatcher(manager, false).PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NotNil(t, result)
+	assert.Equal(t, 1, result.RomCount)
+	assert.Equal(t, 2, result.IgnoredFiles)
+	assert.Equal(t, 0, result.MissingConfigs)
+}
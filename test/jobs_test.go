@@ -0,0 +1,64 @@
+package test
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestParallelScanMatchesSerialScan(t *testing.T) {
+	// logs are written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock a collection with archives, playlists and renamed ROMs which all need scanning
+	manager := NewStubFileManager()
+	romFiles := []string{"Final Fantasy VII (USA).m3u", "Final Fantasy VII (USA) (Disc 1).cue", "notes.txt", ".hidden.n64"}
+	configFiles := []string{"Final Fantasy VII (USA).cfg"}
+	datGames := []string{}
+	for i := 0; i < 200; i++ {
+		romFile := fmt.Sprintf("Game %03d (U).zip", i)
+		romFiles = append(romFiles, romFile)
+		manager.SetArchiveContents(romDirectoryPath, romFile, []string{fmt.Sprintf("Archived Game %03d (USA).n64", i)})
+		configFiles = append(configFiles, fmt.Sprintf("Archived Game %03d (Europe).cfg", i))
+
+		renamedFile := fmt.Sprintf("renamed%03d.n64", i)
+		romFiles = append(romFiles, renamedFile)
+		manager.SetFileContents(romDirectoryPath, renamedFile, []byte(renamedFile))
+		configFiles = append(configFiles, fmt.Sprintf("Renamed Game %03d (USA).cfg", i))
+		datGames = append(datGames, fmt.Sprintf(`<game name="Renamed Game %03d (USA)"><rom name="Renamed Game %03d (USA).n64" crc="%08x"/></game>`, i, i, crc32.ChecksumIEEE([]byte(renamedFile))))
+	}
+	manager.SetDirectoryContents(romDirectoryPath, romFiles)
+	manager.SetFileContents(romDirectoryPath, "Final Fantasy VII (USA).m3u", []byte("Final Fantasy VII (USA) (Disc 1).cue\n"))
+	manager.SetDirectoryContents(configDirectoryPath, configFiles)
+
+	dat, err := patching.ParseDat([]byte("<datafile>" + strings.Join(datGames, "\n") + "</datafile>"))
+	require.NoError(t, err)
+
+	run := func(jobs int) (*patching.PatchResult, string) {
+		patcher := patching.NewPatcher(manager, false, patching.WithRecursiveScan(), patching.WithArchiveInspection(),
+			patching.WithDat(dat), patching.WithRomHashing(), patching.WithJobs(jobs))
+		result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+		require.NoError(t, err)
+
+		log, err := os.ReadFile(result.LogPath)
+		require.NoError(t, err)
+		// the run ID is the only thing which should differ between the logs
+		return result, strings.Replace(string(log), "Run ID: "+result.RunID, "", 1)
+	}
+
+	serialResult, serialLog := run(1)
+	parallelResult, parallelLog := run(8)
+
+	assert.Equal(t, serialLog, parallelLog)
+	assert.Equal(t, serialResult.Report.Entries, parallelResult.Report.Entries)
+	assert.Equal(t, 400, serialResult.CreatedFiles)
+	assert.Len(t, serialResult.Existing, 1)
+	assert.Equal(t, 2, serialResult.IgnoredFiles)
+}
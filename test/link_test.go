@@ -0,0 +1,153 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestLinkModes(t *testing.T) {
+	tt := map[string]struct {
+		linkMode         string
+		expectedLink     bool
+		expectedSymbolic bool
+		expectedContents string
+	}{
+		"copy": {
+			linkMode:         "copy",
+			expectedContents: "input_overlay = \"tetris.cfg\"",
+		},
+		"symlink": {
+			linkMode:         "symlink",
+			expectedLink:     true,
+			expectedSymbolic: true,
+			expectedContents: "input_overlay = \"new-tetris.cfg\"",
+		},
+		"hardlink": {
+			linkMode:         "hardlink",
+			expectedLink:     true,
+			expectedContents: "input_overlay = \"new-tetris.cfg\"",
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			mode, err := patching.ParseLinkMode(tc.linkMode)
+			require.NoError(t, err)
+
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+			manager.SetFileContents(bezelDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \"tetris.cfg\""))
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, patching.WithLinkMode(mode))
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+			link, ok := manager.Link(bezelDirectoryPath, "The New Tetris (USA).cfg")
+			assert.Equal(t, tc.expectedLink, ok)
+			assert.Equal(t, tc.expectedSymbolic, link.symbolic)
+
+			// linked files should follow any changes to their source
+			manager.SetFileContents(bezelDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \"new-tetris.cfg\""))
+			contents, err := manager.ReadFile(bezelDirectoryPath, "The New Tetris (USA).cfg")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedContents, string(contents))
+		})
+	}
+}
+
+func TestUnknownLinkMode(t *testing.T) {
+	_, err := patching.ParseLinkMode("junction")
+	assert.Error(t, err)
+}
+
+func TestRollbackRemovesLinksWhenTheSourceChanges(t *testing.T) {
+	// the manifest is written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+	manager.SetFileContents(configDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \"tetris.cfg\""))
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true, patching.WithLinkMode(patching.LinkModeSymlink))
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+
+	// updating the source changes the link's contents but the link itself is untouched
+	manager.SetFileContents(configDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \"new-tetris.cfg\""))
+	require.NoError(t, patcher.Rollback(configDirectoryPath, ""))
+
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg"}, actualContents)
+}
+
+func TestRollbackRemovesLinksToDeletedSources(t *testing.T) {
+	// the manifest is written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	// run the patcher and then delete the source of the link
+	patcher := patching.NewPatcher(manager, true, patching.WithLinkMode(patching.LinkModeSymlink))
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	require.NoError(t, manager.RemoveFile(configDirectoryPath, "New Tetris, The (USA).cfg"))
+
+	// the dangling link is still removed rather than treated as already removed
+	require.NoError(t, patcher.Rollback(configDirectoryPath, ""))
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.Empty(t, actualContents)
+	_, err = patching.LoadManifest(configDirectoryPath, "")
+	assert.ErrorIs(t, err, patching.ErrNoManifest)
+}
+
+func TestFailedLinksAreReported(t *testing.T) {
+	for _, name := range []string{"symlink", "hardlink"} {
+		t.Run(name, func(t *testing.T) {
+			mode, err := patching.ParseLinkMode(name)
+			require.NoError(t, err)
+
+			// the manifest is written to disk so the config directory must really exist
+			configDirectoryPath := t.TempDir()
+
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "aerofighters assault (U) [!].n64"})
+			manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg"})
+			manager.SetCreateFailure(configDirectoryPath, "The New Tetris (USA).cfg", errors.New("links are not supported"))
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, patching.WithLinkMode(mode))
+			result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+			assert.ErrorIs(t, err, patching.ErrFilesFailed)
+			require.NotNil(t, result)
+
+			require.Len(t, result.Failed, 1)
+			assert.Equal(t, "The New Tetris (USA).cfg", result.Failed[0].NewFile)
+			assert.Equal(t, "links are not supported", result.Failed[0].Reason)
+			_, linked := manager.Link(configDirectoryPath, "The New Tetris (USA).cfg")
+			assert.False(t, linked)
+
+			// the remaining link is still created and recorded with its link mode
+			manifest, err := patching.LoadManifest(configDirectoryPath, "")
+			require.NoError(t, err)
+			require.Len(t, manifest.Files, 1)
+			assert.Equal(t, "aerofighters assault (U) [!].cfg", manifest.Files[0].File)
+			assert.Equal(t, mode, manifest.Files[0].LinkMode)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+// abbreviationMatcher matches ROMs named with a well known abbreviation to the config for the full title
+type abbreviationMatcher map[string]string
+
+func (abbreviationMatcher) Name() string {
+	return "abbreviation"
+}
+
+func (m abbreviationMatcher) Match(rom *patching.Rom, index *patching.ConfigIndex) []patching.Candidate {
+	candidates := []patching.Candidate{}
+	for _, configFile := range index.ByName(m[rom.Name]) {
+		candidates = append(candidates, patching.Candidate{Config: configFile, Confidence: 0.9})
+	}
+	return candidates
+}
+
+func TestCustomMatcher(t *testing.T) {
+	// logs are written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"SM64 (U).n64", "Goldeneye 007 (U).n64", "Wave Race 64 (E).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Super Mario 64 (USA).cfg", "Goldeneye 007 (USA).cfg", "GE007 (USA).cfg"})
+
+	matchers := append([]patching.Matcher{patching.ExactMatcher{}, abbreviationMatcher{"sm64": "super mario 64", "goldeneye 007": "ge007"}}, patching.DefaultMatchers()[2:]...)
+
+	tt := map[string]struct {
+		matchFlag       string
+		expectedCreated []string
+		expectedSection string
+	}{
+		"custom matches are included at a later match level": {
+			matchFlag:       "alternate",
+			expectedCreated: []string{"Goldeneye 007 (U).n64", "SM64 (U).n64"},
+			expectedSection: "NEW FILES (ABBREVIATION MATCHES)\nSM64 (U).n64 -> SM64 (U).cfg",
+		},
+		"custom matches are skipped at an earlier match level": {
+			matchFlag:       "exact",
+			expectedCreated: []string{"Goldeneye 007 (U).n64"},
+			expectedSection: "NEW FILES (ABBREVIATION MATCHES) [SKIPPED]\nSM64 (U).n64 -> SM64 (U).cfg",
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			matchFlag, err := patching.ParseMatchType(tc.matchFlag)
+			require.NoError(t, err)
+
+			patcher := patching.NewPatcher(manager, false, patching.WithMatchers(matchers...))
+			result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, matchFlag)
+			require.NoError(t, err)
+
+			created := []string{}
+			for _, entry := range result.Created {
+				created = append(created, entry.RomFile)
+			}
+			assert.ElementsMatch(t, tc.expectedCreated, created)
+
+			// earlier matchers win over later ones, so the exact Goldeneye config is used rather
+			// than its abbreviation which is kept as an alternative
+			for _, entry := range result.Created {
+				if entry.RomFile == "Goldeneye 007 (U).n64" {
+					assert.Equal(t, "Goldeneye 007 (USA).cfg", entry.ConfigFile)
+					assert.Equal(t, patching.MatchTypeExact, entry.MatchType)
+					assert.Equal(t, []string{"GE007 (USA).cfg"}, entry.Alternatives)
+				}
+			}
+
+			log, err := os.ReadFile(result.LogPath)
+			require.NoError(t, err)
+			assert.Contains(t, string(log), tc.expectedSection)
+		})
+	}
+}
+
+func TestMatchLevelMustBeInTheMatcherChain(t *testing.T) {
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Goldeneye 007 (U).n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"Goldeneye 007 (USA).cfg"})
+
+	patcher := patching.NewPatcher(manager, false, patching.WithMatchers(patching.ExactMatcher{}, patching.AlternateMatcher{}))
+	_, err := patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	assert.ErrorContains(t, err, "match level fuzzy is not one of the matchers")
+
+	patcher = patching.NewPatcher(manager, false, patching.WithMatchers(patching.ExactMatcher{}, patching.ExactMatcher{}))
+	_, err = patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeExact)
+	assert.ErrorContains(t, err, `more than one matcher is named "exact"`)
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestOverrides(t *testing.T) {
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{
+		"Mario Kart 64 (U) [T+Spa].n64",
+		"Wave Race 64 (E).n64",
+		"Goldeneye 007 (U).n64",
+		"Doom 64 (U) [h1C].n64",
+		"Doom 64 (U) [h2C].n64",
+		"Tetrisphere (U).n64",
+	})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{
+		patching.OverridesFileName,
+		"Mario Kart 64 (USA).cfg",
+		"Wave Race 64 (Europe).cfg",
+		"Wave Race 64 (Japan).cfg",
+		"Goldeneye 007 (USA).cfg",
+		"Doom 64 (USA).cfg",
+	})
+	manager.SetFileContents(bezelDirectoryPath, "Wave Race 64 (Japan).cfg", []byte("japan"))
+	manager.SetFileContents(bezelDirectoryPath, patching.OverridesFileName, []byte(`
+overrides:
+  # translations are matched by hand
+  - rom: "Mario Kart 64 (U) [T+*].n64"
+    config: "Mario Kart 64 (USA).cfg"
+  # prefer the Japanese bezel even though the Europe config matches
+  - rom: "Wave Race 64 (E).n64"
+    config: "Wave Race 64 (Japan).cfg"
+  - rom: "Doom 64 (U) [h*].n64"
+    config: ignore
+  - rom: "Tetrisphere (U).n64"
+    config: "Tetrisphere (USA).cfg"
+`))
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true)
+	result, _ := patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeExact)
+	require.NotNil(t, result)
+
+	// overrides are always included whatever the match level
+	created := map[string]patching.ReportEntry{}
+	for _, entry := range result.Created {
+		created[entry.RomFile] = entry
+	}
+	assert.Len(t, created, 3)
+	assert.Equal(t, "Mario Kart 64 (USA).cfg", created["Mario Kart 64 (U) [T+Spa].n64"].ConfigFile)
+	assert.Equal(t, patching.MatchTypeOverride, created["Mario Kart 64 (U) [T+Spa].n64"].MatchType)
+	assert.Equal(t, "Wave Race 64 (Japan).cfg", created["Wave Race 64 (E).n64"].ConfigFile)
+	assert.Equal(t, patching.MatchTypeOverride, created["Wave Race 64 (E).n64"].MatchType)
+	assert.Equal(t, patching.MatchTypeExact, created["Goldeneye 007 (U).n64"].MatchType)
+
+	// ignored ROMs are not matched at all
+	assert.Equal(t, 2, result.IgnoredFiles)
+	assert.Equal(t, 4, result.RomCount)
+	assert.False(t, manager.FileExists(bezelDirectoryPath, "Doom 64 (U) [h1C].cfg"))
+
+	// overrides with a missing config are never matched automatically
+	assert.Equal(t, []string{"Tetrisphere (U).n64"}, result.UnmatchedRoms)
+	for _, entry := range result.Report.Entries {
+		if entry.RomFile == "Tetrisphere (U).n64" {
+			assert.Equal(t, "override config not found: Tetrisphere (USA).cfg", entry.Reason)
+		}
+	}
+
+	// the overridden Europe config no longer has a ROM
+	assert.ElementsMatch(t, []string{"Doom 64 (USA).cfg", "Wave Race 64 (Europe).cfg"}, result.UnmatchedConfigs)
+
+	contents, err := manager.ReadFile(bezelDirectoryPath, "Wave Race 64 (E).cfg")
+	require.NoError(t, err)
+	assert.Equal(t, "japan", string(contents))
+}
+
+func TestInvalidOverrides(t *testing.T) {
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Goldeneye 007 (U).n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{patching.OverridesFileName, "Goldeneye 007 (USA).cfg"})
+	manager.SetFileContents(bezelDirectoryPath, patching.OverridesFileName, []byte("overrides:\n  - rom: \"Goldeneye 007 (U).n64\"\n"))
+
+	patcher := patching.NewPatcher(manager, true)
+	_, err := patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeExact)
+	assert.ErrorContains(t, err, "must have both a rom and a config")
+	assert.False(t, manager.FileExists(bezelDirectoryPath, "Goldeneye 007 (U).cfg"))
+}
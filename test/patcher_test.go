@@ -1,6 +1,7 @@
 package test
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -377,3 +378,270 @@ func TestDirectoryWhichHasTheSameRomWithMultipleExtensions(t *testing.T) {
 
 	assert.ElementsMatch(t, []string{"The New Tetris (USA).cfg", "The New Tetris (U).cfg"}, actualContents)
 }
+
+func TestRecursiveScanning(t *testing.T) {
+	tt := map[string]struct {
+		romDirContents           map[string][]string
+		romFileContents          map[string]string
+		bezelDirContents         []string
+		expectedBezelDirContents []string
+	}{
+		"rom in a game folder": {
+			romDirContents: map[string][]string{
+				"":                    {"Crash Bandicoot (U)"},
+				"Crash Bandicoot (U)": {"Crash Bandicoot (U).cue", "Crash Bandicoot (U).bin"},
+			},
+			romFileContents: map[string]string{
+				"Crash Bandicoot (U)/Crash Bandicoot (U).cue": "FILE \"Crash Bandicoot (U).bin\" BINARY\n  TRACK 01 MODE2/2352\n",
+			},
+			bezelDirContents: []string{"Crash Bandicoot (USA).cfg"},
+			expectedBezelDirContents: []string{
+				"Crash Bandicoot (USA).cfg",
+				"Crash Bandicoot (U).cfg",
+			},
+		},
+		"multi-disc game with a playlist": {
+			romDirContents: map[string][]string{
+				"": {"Final Fantasy VII (U).m3u", "Final Fantasy VII (U)"},
+				"Final Fantasy VII (U)": {
+					"Final Fantasy VII (U) (Disc 1).cue",
+					"Final Fantasy VII (U) (Disc 1).bin",
+					"Final Fantasy VII (U) (Disc 2).cue",
+					"Final Fantasy VII (U) (Disc 2).bin",
+				},
+			},
+			romFileContents: map[string]string{
+				"Final Fantasy VII (U).m3u":                                "Final Fantasy VII (U)/Final Fantasy VII (U) (Disc 1).cue\nFinal Fantasy VII (U)/Final Fantasy VII (U) (Disc 2).cue\n",
+				"Final Fantasy VII (U)/Final Fantasy VII (U) (Disc 1).cue": "FILE \"Final Fantasy VII (U) (Disc 1).bin\" BINARY\n",
+				"Final Fantasy VII (U)/Final Fantasy VII (U) (Disc 2).cue": "FILE \"Final Fantasy VII (U) (Disc 2).bin\" BINARY\n",
+			},
+			bezelDirContents: []string{"Final Fantasy VII (USA).cfg"},
+			expectedBezelDirContents: []string{
+				"Final Fantasy VII (USA).cfg",
+				"Final Fantasy VII (U).cfg",
+			},
+		},
+		"roms in nested folders": {
+			romDirContents: map[string][]string{
+				"":            {"USA", "Japan"},
+				"USA":         {"The New Tetris (U).n64"},
+				"Japan":       {"Hacks"},
+				"Japan/Hacks": {"AeroFighters Assault (J).n64"},
+			},
+			bezelDirContents: []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg"},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"AeroFighters Assault (USA).cfg",
+				"The New Tetris (U).cfg",
+				"AeroFighters Assault (J).cfg",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			for directory, contents := range tc.romDirContents {
+				manager.SetDirectoryContents(filepath.Join(romDirectoryPath, directory), contents)
+			}
+			for path, contents := range tc.romFileContents {
+				manager.SetFileContents(romDirectoryPath, path, []byte(contents))
+			}
+			manager.SetDirectoryContents(bezelDirectoryPath, tc.bezelDirContents)
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, patching.WithRecursiveScan())
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+			// check the contents of the bezel directory to ensure the expected config files exist
+			actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectedBezelDirContents, actualContents)
+		})
+	}
+}
+
+func TestArchiveInspection(t *testing.T) {
+	tt := map[string]struct {
+		romDirContents           []string
+		archiveContents          map[string][]string
+		bezelDirContents         []string
+		expectedBezelDirContents []string
+	}{
+		"archive with a differently named rom": {
+			romDirContents: []string{"tetris.zip"},
+			archiveContents: map[string][]string{
+				"tetris.zip": {"New Tetris, The (USA).n64"},
+			},
+			bezelDirContents: []string{"New Tetris, The (USA).cfg"},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"tetris.cfg",
+			},
+		},
+		"archive with a rom in a subfolder": {
+			romDirContents: []string{"tetris.zip"},
+			archiveContents: map[string][]string{
+				"tetris.zip": {"roms/The New Tetris (U) [!].n64"},
+			},
+			bezelDirContents: []string{"New Tetris, The (USA).cfg"},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"tetris.cfg",
+			},
+		},
+		"unreadable archive": {
+			romDirContents:   []string{"tetris.zip"},
+			bezelDirContents: []string{"New Tetris, The (USA).cfg"},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, tc.romDirContents)
+			manager.SetDirectoryContents(bezelDirectoryPath, tc.bezelDirContents)
+			for archive, contents := range tc.archiveContents {
+				manager.SetArchiveContents(romDirectoryPath, archive, contents)
+			}
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, patching.WithArchiveInspection())
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+			// check the contents of the bezel directory to ensure the expected config files exist
+			actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectedBezelDirContents, actualContents)
+		})
+	}
+}
+
+func TestScoredMatching(t *testing.T) {
+	tt := map[string]struct {
+		matchLevel               string
+		opts                     []patching.Option
+		expectedBezelDirContents []string
+	}{
+		"scored matches are created": {
+			matchLevel: "scored",
+			expectedBezelDirContents: []string{
+				"Spider-Man (USA).cfg",
+				"Star Wars - Rogue Squadron (USA).cfg",
+				"Spiderman (U).cfg",
+				"Star Wars - Rogue Squadon (U).cfg",
+			},
+		},
+		"scored matches are skipped by fuzzy matching": {
+			matchLevel: "fuzzy",
+			expectedBezelDirContents: []string{
+				"Spider-Man (USA).cfg",
+				"Star Wars - Rogue Squadron (USA).cfg",
+			},
+		},
+		"scores below the minimum are not matched": {
+			matchLevel: "scored",
+			opts:       []patching.Option{patching.WithMinimumScore(0.99)},
+			expectedBezelDirContents: []string{
+				"Spider-Man (USA).cfg",
+				"Star Wars - Rogue Squadron (USA).cfg",
+				"Spiderman (U).cfg",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"Spiderman (U).n64", "Star Wars - Rogue Squadon (U).n64"})
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{"Spider-Man (USA).cfg", "Star Wars - Rogue Squadron (USA).cfg"})
+
+			matchFlag, err := patching.ParseMatchType(tc.matchLevel)
+			require.NoError(t, err)
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, tc.opts...)
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, matchFlag)
+
+			// check the contents of the bezel directory to ensure the expected config files exist
+			actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectedBezelDirContents, actualContents)
+		})
+	}
+}
+
+func TestConfiguredArticles(t *testing.T) {
+	tt := map[string]struct {
+		opts                     []patching.Option
+		expectedBezelDirContents []string
+	}{
+		"unknown articles are not moved": {
+			expectedBezelDirContents: []string{"Vliegende Hollander, De (Netherlands).cfg"},
+		},
+		"configured articles are moved": {
+			opts: []patching.Option{patching.WithArticles(map[string][]string{"nl": {"de", "het"}})},
+			expectedBezelDirContents: []string{
+				"Vliegende Hollander, De (Netherlands).cfg",
+				"De Vliegende Hollander (Netherlands).cfg",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"De Vliegende Hollander (Netherlands).gba"})
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{"Vliegende Hollander, De (Netherlands).cfg"})
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, tc.opts...)
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+
+			// check the contents of the bezel directory to ensure the expected config files exist
+			actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectedBezelDirContents, actualContents)
+		})
+	}
+}
+
+func TestPatchResult(t *testing.T) {
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "AeroFighters Assault (USA).n64", "Goldeneye 007 (U) [!].n64", "aerofighters assault (U) [!].n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg", "Wave Race 64 (USA).cfg"})
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, false)
+	result, _ := patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeExact)
+	require.NotNil(t, result)
+
+	require.Len(t, result.Created, 1)
+	assert.Equal(t, "aerofighters assault (U) [!].cfg", result.Created[0].NewFile)
+	assert.Equal(t, "AeroFighters Assault (USA).cfg", result.Created[0].ConfigFile)
+	require.Len(t, result.Skipped, 1)
+	assert.Equal(t, "The New Tetris (USA).cfg", result.Skipped[0].NewFile)
+	require.Len(t, result.Existing, 1)
+	assert.Equal(t, "AeroFighters Assault (USA).n64", result.Existing[0].RomFile)
+	assert.Equal(t, []string{"Goldeneye 007 (U) [!].n64"}, result.UnmatchedRoms)
+	assert.Equal(t, []string{"Wave Race 64 (USA).cfg"}, result.UnmatchedConfigs)
+	assert.Equal(t, 1, result.CreatedFiles)
+	assert.Equal(t, 1, result.SkippedFiles)
+
+	// nothing should have been written during a dry run
+	actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg", "Wave Race 64 (USA).cfg"}, actualContents)
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestPlanAndApply(t *testing.T) {
+	// plans and manifests are written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Goldeneye 007 (U).n64", "Wave Race 64 (E).n64", "Wave Race 64 (E).z64", "Tetrisphere (U).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Goldeneye 007 (USA).cfg", "Wave Race 64 (Europe).cfg"})
+
+	// a dry run plans the files without creating them
+	planner := patching.NewPatcher(manager, false, patching.WithFallbackTemplate(patching.FallbackTemplate{Name: "fallback.cfg", Contents: "# {rom_name}"}))
+	result, err := planner.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NoError(t, err)
+	assert.False(t, manager.FileExists(configDirectoryPath, "Goldeneye 007 (U).cfg"))
+
+	// both Wave Race ROMs use the same config so it is only planned once
+	assert.Equal(t, []patching.PlanOperation{
+		{File: "Goldeneye 007 (U).cfg", Source: "Goldeneye 007 (USA).cfg", Rom: "Goldeneye 007 (U).n64", MatchType: patching.MatchTypeExact, Score: 1, LinkMode: patching.LinkModeCopy},
+		{File: "Wave Race 64 (E).cfg", Source: "Wave Race 64 (Europe).cfg", Rom: "Wave Race 64 (E).n64", MatchType: patching.MatchTypeExact, Score: 1, LinkMode: patching.LinkModeCopy},
+		{File: "Tetrisphere (U).cfg", Source: "fallback.cfg", Rom: "Tetrisphere (U).n64", MatchType: patching.MatchTypeTemplate, Contents: "# Tetrisphere (U)"},
+	}, result.Plan.Operations)
+
+	// plans survive being written to disk and edited
+	planPath := filepath.Join(configDirectoryPath, patching.PlanFileName(result.RunID))
+	require.NoError(t, result.Plan.Save(planPath))
+	plan, err := patching.LoadPlan(planPath)
+	require.NoError(t, err)
+	plan.Operations = plan.Operations[1:]
+
+	// applying the plan creates exactly the planned files
+	applier := patching.NewPatcher(manager, true)
+	require.NoError(t, applier.Apply(plan))
+	assert.False(t, manager.FileExists(configDirectoryPath, "Goldeneye 007 (U).cfg"))
+	assert.True(t, manager.FileExists(configDirectoryPath, "Wave Race 64 (E).cfg"))
+	contents, err := manager.ReadFile(configDirectoryPath, "Tetrisphere (U).cfg")
+	require.NoError(t, err)
+	assert.Equal(t, "# Tetrisphere (U)", string(contents))
+
+	// applied plans are recorded so they can be rolled back
+	manifest, err := patching.LoadManifest(configDirectoryPath, "")
+	require.NoError(t, err)
+	assert.Len(t, manifest.Files, 2)
+}
+
+func TestApplyOutdatedPlan(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Goldeneye 007 (U).n64", "Wave Race 64 (E).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Goldeneye 007 (USA).cfg", "Wave Race 64 (Europe).cfg"})
+
+	planner := patching.NewPatcher(manager, false)
+	result, err := planner.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NoError(t, err)
+	require.Len(t, result.Plan.Operations, 2)
+
+	// the directories change after the plan was made
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Goldeneye 007 (USA).cfg", "Wave Race 64 (E).cfg"})
+
+	applier := patching.NewPatcher(manager, true)
+	err = applier.Apply(result.Plan)
+	assert.ErrorIs(t, err, patching.ErrPlanOutdated)
+	assert.ErrorContains(t, err, "Wave Race 64 (E).cfg: source Wave Race 64 (Europe).cfg no longer exists")
+	assert.ErrorContains(t, err, "Wave Race 64 (E).cfg: already exists")
+
+	// nothing is created when any precondition fails
+	assert.False(t, manager.FileExists(configDirectoryPath, "Goldeneye 007 (U).cfg"))
+}
+
+func TestApplyInvalidPlan(t *testing.T) {
+	tt := map[string]patching.PlanOperation{
+		"file outside the config directory": {File: "../Goldeneye 007 (U).cfg", Source: "Goldeneye 007 (USA).cfg", MatchType: patching.MatchTypeExact},
+		"missing source":                    {File: "Goldeneye 007 (U).cfg", MatchType: patching.MatchTypeExact},
+	}
+
+	for name, op := range tt {
+		t.Run(name, func(t *testing.T) {
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{"Goldeneye 007 (USA).cfg"})
+
+			patcher := patching.NewPatcher(manager, true)
+			err := patcher.Apply(&patching.Plan{ConfigDirectory: bezelDirectoryPath, Operations: []patching.PlanOperation{op}})
+			assert.Error(t, err)
+			assert.False(t, manager.FileExists(bezelDirectoryPath, "Goldeneye 007 (U).cfg"))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestRegionPreference(t *testing.T) {
+	tt := map[string]struct {
+		romFileName      string
+		opts             []patching.Option
+		expectedConfig   string
+		expectedContents string
+	}{
+		"default priority prefers USA": {
+			romFileName:      "Wave Race 64 (Unl).n64",
+			expectedConfig:   "Wave Race 64 (Unl).cfg",
+			expectedContents: "usa",
+		},
+		"config from the same region as the rom": {
+			romFileName:      "Wave Race 64 (E) [!].n64",
+			expectedConfig:   "Wave Race 64 (E) [!].cfg",
+			expectedContents: "europe",
+		},
+		"custom priority": {
+			romFileName:      "Wave Race 64 (Unl).n64",
+			expectedConfig:   "Wave Race 64 (Unl).cfg",
+			opts:             []patching.Option{patching.WithRegionPriority("J", "USA")},
+			expectedContents: "japan",
+		},
+		"regions do not change which titles match": {
+			romFileName:      "Wave Race 64 - Shindou Edition (J).n64",
+			expectedConfig:   "Wave Race 64 - Shindou Edition (J).cfg",
+			expectedContents: "shindou",
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{tc.romFileName})
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{
+				"Wave Race 64 (Japan).cfg",
+				"Wave Race 64 (Europe).cfg",
+				"Wave Race 64 (USA).cfg",
+				"Wave Race 64 - Shindou Edition (USA).cfg",
+			})
+			manager.SetFileContents(bezelDirectoryPath, "Wave Race 64 (Japan).cfg", []byte("japan"))
+			manager.SetFileContents(bezelDirectoryPath, "Wave Race 64 (Europe).cfg", []byte("europe"))
+			manager.SetFileContents(bezelDirectoryPath, "Wave Race 64 (USA).cfg", []byte("usa"))
+			manager.SetFileContents(bezelDirectoryPath, "Wave Race 64 - Shindou Edition (USA).cfg", []byte("shindou"))
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, tc.opts...)
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+			// check the new config was copied from the preferred config
+			contents, err := manager.ReadFile(bezelDirectoryPath, tc.expectedConfig)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedContents, string(contents))
+		})
+	}
+}
+
+func TestAlternativeConfigsAreNotMissingRoms(t *testing.T) {
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Wave Race 64 (U).n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"Wave Race 64 (Japan).cfg", "Wave Race 64 (USA).cfg"})
+
+	result, _ := patching.NewPatcher(manager, false).PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NotNil(t, result)
+	assert.Equal(t, 0, result.MissingRoms)
+	assert.Equal(t, 1, result.CreatedFiles)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestGeneratedConfigsAreNotUsedAsSources(t *testing.T) {
+	// manifests are written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Goldeneye 007 (U).n64", "Wave Race 64 (E).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Goldeneye 007 (USA).cfg", "Wave Race 64 (Europe).cfg"})
+
+	// the first run creates a config for each ROM
+	patcher := patching.NewPatcher(manager, true)
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.CreatedFiles)
+	assert.Equal(t, 0, result.GeneratedConfigs)
+
+	// a hacked ROM is added and the Wave Race ROM is deleted
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Goldeneye 007 (U).n64", "Goldeneye 007 (U) [h1C].n64"})
+
+	tt := map[string]struct {
+		opts                     []patching.Option
+		expectedSource           string
+		expectedUnmatchedConfigs []string
+	}{
+		"generated configs are excluded by default": {
+			expectedSource:           "Goldeneye 007 (USA).cfg",
+			expectedUnmatchedConfigs: []string{"Wave Race 64 (Europe).cfg"},
+		},
+		"generated configs can be included": {
+			opts:           []patching.Option{patching.WithGeneratedSources()},
+			expectedSource: "Goldeneye 007 (U).cfg",
+			// the generated config for the deleted ROM has no ROM either
+			expectedUnmatchedConfigs: []string{"Wave Race 64 (E).cfg", "Wave Race 64 (Europe).cfg"},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			patcher := patching.NewPatcher(manager, false, tc.opts...)
+			result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+			require.NoError(t, err)
+
+			// originals and generated configs are counted separately
+			assert.Equal(t, 4, result.ConfigCount)
+			assert.Equal(t, 2, result.GeneratedConfigs)
+
+			require.Len(t, result.Created, 1)
+			assert.Equal(t, "Goldeneye 007 (U) [h1C].n64", result.Created[0].RomFile)
+			assert.Equal(t, tc.expectedSource, result.Created[0].ConfigFile)
+			assert.Equal(t, tc.expectedUnmatchedConfigs, result.UnmatchedConfigs)
+
+			log, err := os.ReadFile(result.LogPath)
+			require.NoError(t, err)
+			assert.Contains(t, string(log), "Found 2 original and 2 generated config files")
+		})
+	}
+
+	// excluded configs are reported along with the run which generated them
+	patcher = patching.NewPatcher(manager, false)
+	result, err = patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NoError(t, err)
+	generated := []string{}
+	for _, entry := range result.Report.Entries {
+		if entry.Status == patching.ReportStatusGenerated {
+			generated = append(generated, entry.ConfigFile)
+			assert.Contains(t, entry.Reason, "generated by run ")
+		}
+	}
+	assert.Equal(t, []string{"Goldeneye 007 (U).cfg", "Wave Race 64 (E).cfg"}, generated)
+}
@@ -0,0 +1,204 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestPruneQuarantinesOrphanedConfigs(t *testing.T) {
+	// the manifest is written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "aerofighters assault (U) [!].n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg", "Goldeneye 007 (USA).cfg"})
+
+	// run the patcher and then remove one of the ROMs
+	patcher := patching.NewPatcher(manager, true)
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	manager.SetDirectoryContents(romDirectoryPath, []string{"aerofighters assault (U) [!].n64"})
+
+	require.NoError(t, patcher.Prune(configDirectoryPath))
+
+	// the original configs are never pruned, even without a ROM
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg", "Goldeneye 007 (USA).cfg", "aerofighters assault (U) [!].cfg"}, actualContents)
+
+	// pruned configs are quarantined under the run which created them
+	quarantineContents, err := manager.GetDirectoryContents(filepath.Join(configDirectoryPath, patching.QuarantineDirectory, result.RunID))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"The New Tetris (USA).cfg"}, quarantineContents)
+
+	// the pruned file should no longer be tracked by the manifest
+	manifest, err := patching.LoadManifest(configDirectoryPath, "")
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, "aerofighters assault (U) [!].cfg", manifest.Files[0].File)
+}
+
+func TestPruneQuarantinesLinksToDeletedSources(t *testing.T) {
+	// the manifest is written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	// run the patcher and then remove both the ROM and the source of the link
+	patcher := patching.NewPatcher(manager, true, patching.WithLinkMode(patching.LinkModeSymlink))
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	manager.SetDirectoryContents(romDirectoryPath, []string{})
+	require.NoError(t, manager.RemoveFile(configDirectoryPath, "New Tetris, The (USA).cfg"))
+
+	require.NoError(t, patcher.Prune(configDirectoryPath))
+
+	// the dangling link is quarantined rather than forgotten
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.Empty(t, actualContents)
+	quarantineContents, err := manager.GetDirectoryContents(filepath.Join(configDirectoryPath, patching.QuarantineDirectory, result.RunID))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"The New Tetris (USA).cfg"}, quarantineContents)
+}
+
+func TestPruneReportsFilesWhichCannotBeMoved(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	// run the patcher, remove the ROM and then stop the created file from being moved
+	patcher := patching.NewPatcher(manager, true)
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	manager.SetDirectoryContents(romDirectoryPath, []string{})
+	manager.SetRemoveFailure(configDirectoryPath, "The New Tetris (USA).cfg", errors.New("permission denied"))
+
+	err = patcher.Prune(configDirectoryPath)
+	assert.ErrorIs(t, err, patching.ErrMoveFailed)
+
+	// the file which could not be moved is still tracked so the prune can be retried
+	manifest, err := patching.LoadManifest(configDirectoryPath, "")
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, "The New Tetris (USA).cfg", manifest.Files[0].File)
+
+	logs, err := filepath.Glob(filepath.Join(configDirectoryPath, "prune-log.*.log"))
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	log, err := os.ReadFile(logs[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "FAILED FILES (NOT MOVED)\nThe New Tetris (USA).cfg (permission denied)")
+}
+
+func TestPruneDryRun(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	_, err := patching.NewPatcher(manager, true).PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	manager.SetDirectoryContents(romDirectoryPath, []string{})
+
+	// pruning without committing should not touch any files
+	require.NoError(t, patching.NewPatcher(manager, false).Prune(configDirectoryPath))
+
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "The New Tetris (USA).cfg"}, actualContents)
+}
+
+func TestPruneSkipsMissingRomDirectories(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	patcher := patching.NewPatcher(manager, true)
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+
+	// an unmounted ROM drive should not look like every ROM has been deleted
+	manager = NewStubFileManager()
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "The New Tetris (USA).cfg"})
+	require.NoError(t, patching.NewPatcher(manager, true).Prune(configDirectoryPath))
+
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "The New Tetris (USA).cfg"}, actualContents)
+}
+
+func TestPruneKeepsEachRunsQuarantinedConfigs(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+	manager.SetFileContents(configDirectoryPath, "New Tetris, The (USA).cfg", []byte("first"))
+
+	// the same config is created, and pruned, by two different runs
+	patcher := patching.NewPatcher(manager, true)
+	runIDs := []string{}
+	for _, contents := range []string{"first", "second"} {
+		manager.SetFileContents(configDirectoryPath, "New Tetris, The (USA).cfg", []byte(contents))
+		manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+		result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+		require.NoError(t, err)
+		require.Equal(t, 1, result.CreatedFiles)
+		runIDs = append(runIDs, result.RunID)
+
+		manager.SetDirectoryContents(romDirectoryPath, []string{})
+		require.NoError(t, patcher.Prune(configDirectoryPath))
+	}
+	require.NotEqual(t, runIDs[0], runIDs[1])
+
+	// neither quarantined copy overwrites the other
+	for i, contents := range []string{"first", "second"} {
+		quarantined, err := manager.ReadFile(filepath.Join(configDirectoryPath, patching.QuarantineDirectory, runIDs[i]), "The New Tetris (USA).cfg")
+		require.NoError(t, err)
+		assert.Equal(t, contents, string(quarantined))
+	}
+}
+
+func TestPruneKeepsModifiedConfigs(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	patcher := patching.NewPatcher(manager, true)
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+
+	// the user edits the new config and then removes the ROM
+	manager.SetFileContents(configDirectoryPath, "The New Tetris (USA).cfg", []byte("edited"))
+	manager.SetDirectoryContents(romDirectoryPath, []string{})
+	require.NoError(t, patcher.Prune(configDirectoryPath))
+
+	assert.True(t, manager.FileExists(configDirectoryPath, "The New Tetris (USA).cfg"))
+	manifest, err := patching.LoadManifest(configDirectoryPath, "")
+	require.NoError(t, err)
+	assert.Len(t, manifest.Files, 1)
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestReportEntries(t *testing.T) {
+	tt := map[string]struct {
+		matchFlag      string
+		expectedStatus map[string]string
+	}{
+		"alternate matches are created": {
+			matchFlag: "alternate",
+			expectedStatus: map[string]string{
+				"The New Tetris (USA).n64":       "created",
+				"AeroFighters Assault (USA).n64": "existing",
+				"Goldeneye 007 (U).n64":          "unmatched",
+				"Wave Race 64 (USA).cfg":         "unmatched",
+				"Goldeneye 007 (U).srm":          "ignored",
+			},
+		},
+		"alternate matches are skipped": {
+			matchFlag: "exact",
+			expectedStatus: map[string]string{
+				"The New Tetris (USA).n64":       "skipped",
+				"AeroFighters Assault (USA).n64": "existing",
+				"Goldeneye 007 (U).n64":          "unmatched",
+				"Wave Race 64 (USA).cfg":         "unmatched",
+				"Goldeneye 007 (U).srm":          "ignored",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "AeroFighters Assault (USA).n64", "Goldeneye 007 (U).n64", "Goldeneye 007 (U).srm"})
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg", "Wave Race 64 (USA).cfg"})
+
+			matchFlag, err := patching.ParseMatchType(tc.matchFlag)
+			require.NoError(t, err)
+
+			// run the patcher
+			result, _ := patching.NewPatcher(manager, true).PatchDirectory(bezelDirectoryPath, romDirectoryPath, matchFlag)
+			require.NotNil(t, result)
+			require.NotNil(t, result.Report)
+
+			// unmatched configs have no ROM so they are keyed by the config file
+			actualStatus := map[string]string{}
+			for _, entry := range result.Report.Entries {
+				key := entry.RomFile
+				if key == "" {
+					key = entry.ConfigFile
+				}
+				actualStatus[key] = string(entry.Status)
+			}
+			assert.Equal(t, tc.expectedStatus, actualStatus)
+		})
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	report := &patching.Report{
+		RunID: "1234",
+		Entries: []patching.ReportEntry{{
+			ConfigFile: "New Tetris, The (USA).cfg",
+			RomFile:    "The New Tetris (USA).n64",
+			NewFile:    "The New Tetris (USA).cfg",
+			MatchType:  patching.MatchTypeAlternate,
+			Status:     patching.ReportStatusCreated,
+		}},
+	}
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, report.Write(buffer, patching.ReportFormatJSON))
+	decoded := &patching.Report{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), decoded))
+	assert.Equal(t, report, decoded)
+
+	buffer.Reset()
+	require.NoError(t, report.Write(buffer, patching.ReportFormatCSV))
+	rows, err := csv.NewReader(buffer).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"config_file", "rom_file", "new_file", "match_type", "score", "status", "reason"},
+		{"New Tetris, The (USA).cfg", "The New Tetris (USA).n64", "The New Tetris (USA).cfg", "alternate", "", "created", ""},
+	}, rows)
+
+	assert.Error(t, report.Write(buffer, patching.ReportFormatText))
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+// stubReviewer answers reviews from a map of ROM paths to decisions and records what it was asked
+type stubReviewer struct {
+	decisions map[string]patching.Decision
+	reviewed  []string
+	// stopAfter makes the reviewer fail like a closed terminal after answering this many reviews
+	stopAfter int
+}
+
+func (r *stubReviewer) Review(item patching.ReviewItem) (patching.Decision, error) {
+	if r.stopAfter > 0 && len(r.reviewed) >= r.stopAfter {
+		return patching.Decision{}, io.ErrUnexpectedEOF
+	}
+	r.reviewed = append(r.reviewed, item.Rom)
+	decision := r.decisions[item.Rom]
+	if decision.Action == patching.DecisionAccept && decision.Config == "" {
+		decision.Config = item.Candidates[0].ConfigFile
+	}
+	return decision, nil
+}
+
+// pickingReviewer accepts the candidate at the given position for every review
+type pickingReviewer struct {
+	pick  int
+	items []patching.ReviewItem
+}
+
+func (r *pickingReviewer) Review(item patching.ReviewItem) (patching.Decision, error) {
+	r.items = append(r.items, item)
+	return patching.Decision{Action: patching.DecisionAccept, Config: item.Candidates[r.pick].ConfigFile}, nil
+}
+
+func TestInteractiveReview(t *testing.T) {
+	// decisions are saved to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{
+		"AeroFighters Assault (U).n64",
+		"The New Tetris (USA).n64",
+		"Wave Race 64 (E).n64",
+		"Goldeneye 007 (U).n64",
+		"Conkers Bad Fur Day (U).n64",
+	})
+	manager.SetDirectoryContents(configDirectoryPath, []string{
+		"AeroFighters Assault (USA).cfg",
+		"New Tetris, The (USA).cfg",
+		"Wave Race 64 (Japan).cfg",
+		"Wave Race 64 (Europe).cfg",
+		"Goldeneye 007 (USA).cfg",
+		"Conker's Bad Fur Day (USA).cfg",
+	})
+	manager.SetFileContents(configDirectoryPath, "Wave Race 64 (Japan).cfg", []byte("japan"))
+
+	// run the patcher
+	reviewer := &stubReviewer{decisions: map[string]patching.Decision{
+		"The New Tetris (USA).n64":    {Action: patching.DecisionReject},
+		"Wave Race 64 (E).n64":        {Action: patching.DecisionAccept, Config: "Wave Race 64 (Japan).cfg"},
+		"Conkers Bad Fur Day (U).n64": {Action: patching.DecisionSkip},
+	}}
+	patcher := patching.NewPatcher(manager, true, patching.WithReviewer(reviewer))
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+
+	// single exact matches are never reviewed
+	assert.ElementsMatch(t, []string{"The New Tetris (USA).n64", "Wave Race 64 (E).n64", "Conkers Bad Fur Day (U).n64"}, reviewer.reviewed)
+	require.Len(t, result.Rejected, 1)
+	assert.Equal(t, "The New Tetris (USA).n64", result.Rejected[0].RomFile)
+
+	// only the accepted matches should be created, using the picked candidate
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.Contains(t, actualContents, "AeroFighters Assault (U).cfg")
+	assert.Contains(t, actualContents, "Goldeneye 007 (U).cfg")
+	assert.NotContains(t, actualContents, "The New Tetris (USA).cfg")
+	assert.NotContains(t, actualContents, "Conkers Bad Fur Day (U).cfg")
+	contents, err := manager.ReadFile(configDirectoryPath, "Wave Race 64 (E).cfg")
+	require.NoError(t, err)
+	assert.Equal(t, "japan", string(contents))
+
+	// a re-run should reuse the saved decisions and only ask about the skipped match again
+	reviewer.reviewed = nil
+	result, err = patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Conkers Bad Fur Day (U).n64"}, reviewer.reviewed)
+	assert.Len(t, result.Rejected, 1)
+}
+
+func TestInterruptedReviewKeepsAcceptedMatches(t *testing.T) {
+	// decisions are saved to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"AeroFighters Assault (U).n64", "The New Tetris (USA).n64", "Conkers Bad Fur Day (U).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"AeroFighters Assault (USA).cfg", "New Tetris, The (USA).cfg", "Conker's Bad Fur Day (USA).cfg"})
+
+	// the user accepts the first match and then closes the terminal
+	reviewer := &stubReviewer{decisions: map[string]patching.Decision{
+		"The New Tetris (USA).n64":    {Action: patching.DecisionAccept},
+		"Conkers Bad Fur Day (U).n64": {Action: patching.DecisionAccept},
+	}, stopAfter: 1}
+	patcher := patching.NewPatcher(manager, true, patching.WithReviewer(reviewer))
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	require.Len(t, reviewer.reviewed, 1)
+
+	// the accepted match is still created and the unreviewed match is left for the next run
+	created := []string{}
+	for _, entry := range result.Created {
+		created = append(created, entry.RomFile)
+	}
+	assert.ElementsMatch(t, []string{"AeroFighters Assault (U).n64", reviewer.reviewed[0]}, created)
+	require.Len(t, result.Skipped, 1)
+	assert.Contains(t, result.Skipped[0].Reason, "not reviewed, the review stopped early")
+
+	// only the answered review is remembered
+	reviewer.reviewed, reviewer.stopAfter = nil, 0
+	_, err = patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{result.Skipped[0].RomFile}, reviewer.reviewed)
+}
+
+func TestFailedAcceptedMatchesAreReviewedAgain(t *testing.T) {
+	// decisions are saved to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "Conkers Bad Fur Day (U).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "Conker's Bad Fur Day (USA).cfg"})
+	manager.SetCreateFailure(configDirectoryPath, "The New Tetris (USA).cfg", errors.New("read-only file system"))
+
+	// the accepted match cannot be created
+	reviewer := &stubReviewer{decisions: map[string]patching.Decision{
+		"The New Tetris (USA).n64":    {Action: patching.DecisionAccept},
+		"Conkers Bad Fur Day (U).n64": {Action: patching.DecisionReject},
+	}}
+	patcher := patching.NewPatcher(manager, true, patching.WithReviewer(reviewer))
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	assert.ErrorIs(t, err, patching.ErrFilesFailed)
+	require.Len(t, result.Failed, 1)
+
+	// only the rejection is remembered so the failed match is asked about again
+	reviewer.reviewed = nil
+	_, err = patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	assert.ErrorIs(t, err, patching.ErrFilesFailed)
+	assert.Equal(t, []string{"The New Tetris (USA).n64"}, reviewer.reviewed)
+}
+
+func TestDryRunReviewDecisionsAreNotSaved(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	reviewer := &stubReviewer{decisions: map[string]patching.Decision{"The New Tetris (USA).n64": {Action: patching.DecisionReject}}}
+	patcher := patching.NewPatcher(manager, false, patching.WithReviewer(reviewer))
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	assert.Len(t, result.Rejected, 1)
+
+	// a dry run never changes the config directory so the match is reviewed again next time
+	_, err = os.Stat(filepath.Join(configDirectoryPath, "patch-decisions.json"))
+	assert.True(t, os.IsNotExist(err))
+	reviewer.reviewed = nil
+	_, err = patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"The New Tetris (USA).n64"}, reviewer.reviewed)
+}
+
+func TestReviewOnlyOffersCandidatesAtTheMatchLevel(t *testing.T) {
+	// decisions are saved to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"Conkers Bad Fur Day (U).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"Conkers Bad Fur Day (Europe).cfg", "Conkers Bad Fur Day (Japan).cfg", "Conker's Bad Fur Day (USA).cfg"})
+	manager.SetFileContents(configDirectoryPath, "Conkers Bad Fur Day (Japan).cfg", []byte("japan"))
+
+	// the user picks the second candidate
+	reviewer := &pickingReviewer{pick: 1}
+	patcher := patching.NewPatcher(manager, true, patching.WithReviewer(reviewer))
+	result, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeAlternate)
+	require.NoError(t, err)
+
+	// the fuzzy match would be skipped at the alternate level so it is never offered
+	require.Len(t, reviewer.items, 1)
+	candidates := []string{}
+	for _, candidate := range reviewer.items[0].Candidates {
+		candidates = append(candidates, candidate.ConfigFile)
+	}
+	assert.Equal(t, []string{"Conkers Bad Fur Day (Europe).cfg", "Conkers Bad Fur Day (Japan).cfg"}, candidates)
+
+	// the picked candidate is created and the other configs lost to the pick
+	require.Len(t, result.Created, 1)
+	contents, err := manager.ReadFile(configDirectoryPath, "Conkers Bad Fur Day (U).cfg")
+	require.NoError(t, err)
+	assert.Equal(t, "japan", string(contents))
+	for _, entry := range result.Report.Entries {
+		if entry.Status == patching.ReportStatusAlternative {
+			assert.Equal(t, "Conkers Bad Fur Day (Japan).cfg was picked in the review", entry.Reason)
+		}
+	}
+}
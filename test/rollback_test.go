@@ -0,0 +1,127 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestRollbackRemovesCreatedFiles(t *testing.T) {
+	// the manifest is written to disk so the config directory must really exist
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "aerofighters assault (U) [!].n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg"})
+	manager.SetFileContents(configDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \"tetris.cfg\""))
+	manager.SetFileContents(configDirectoryPath, "AeroFighters Assault (USA).cfg", []byte("input_overlay = \"aerofighters.cfg\""))
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true)
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+
+	manifest, err := patching.LoadManifest(configDirectoryPath, "")
+	require.NoError(t, err)
+	assert.Len(t, manifest.Files, 2)
+
+	// roll back the run
+	require.NoError(t, patcher.Rollback(configDirectoryPath, manifest.RunID))
+
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg"}, actualContents)
+
+	// the manifest should be removed once every file has been rolled back
+	_, err = patching.LoadManifest(configDirectoryPath, manifest.RunID)
+	assert.ErrorIs(t, err, patching.ErrNoManifest)
+}
+
+func TestRollbackKeepsModifiedFiles(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "aerofighters assault (U) [!].n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg"})
+	manager.SetFileContents(configDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \"tetris.cfg\""))
+	manager.SetFileContents(configDirectoryPath, "AeroFighters Assault (USA).cfg", []byte("input_overlay = \"aerofighters.cfg\""))
+
+	// run the patcher and then modify one of the created files
+	patcher := patching.NewPatcher(manager, true)
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	manager.SetFileContents(configDirectoryPath, "The New Tetris (USA).cfg", []byte("input_overlay = \"custom.cfg\""))
+
+	require.NoError(t, patcher.Rollback(configDirectoryPath, ""))
+
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg", "The New Tetris (USA).cfg"}, actualContents)
+
+	// the modified file should still be tracked by the manifest
+	manifest, err := patching.LoadManifest(configDirectoryPath, "")
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, "The New Tetris (USA).cfg", manifest.Files[0].File)
+}
+
+func TestRollbackDryRun(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+
+	_, err := patching.NewPatcher(manager, true).PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+
+	// rolling back without committing should not touch any files
+	require.NoError(t, patching.NewPatcher(manager, false).Rollback(configDirectoryPath, ""))
+
+	actualContents, err := manager.GetDirectoryContents(configDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "The New Tetris (USA).cfg"}, actualContents)
+
+	_, err = patching.LoadManifest(configDirectoryPath, "")
+	assert.NoError(t, err)
+}
+
+func TestRollbackReportsFilesWhichCannotBeRemoved(t *testing.T) {
+	configDirectoryPath := t.TempDir()
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64", "aerofighters assault (U) [!].n64"})
+	manager.SetDirectoryContents(configDirectoryPath, []string{"New Tetris, The (USA).cfg", "AeroFighters Assault (USA).cfg"})
+
+	// run the patcher and then stop one of the created files from being removed
+	patcher := patching.NewPatcher(manager, true)
+	_, err := patcher.PatchDirectory(configDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+	require.NoError(t, err)
+	manager.SetRemoveFailure(configDirectoryPath, "The New Tetris (USA).cfg", errors.New("permission denied"))
+
+	err = patcher.Rollback(configDirectoryPath, "")
+	assert.ErrorIs(t, err, patching.ErrRemoveFailed)
+
+	// the file which could not be removed is still tracked so the rollback can be retried
+	manifest, err := patching.LoadManifest(configDirectoryPath, "")
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, "The New Tetris (USA).cfg", manifest.Files[0].File)
+
+	logs, err := filepath.Glob(filepath.Join(configDirectoryPath, "rollback-log.*.log"))
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	log, err := os.ReadFile(logs[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "FAILED FILES (NOT REMOVED)\nThe New Tetris (USA).cfg (permission denied)")
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+func TestFallbackTemplate(t *testing.T) {
+	// the system name is taken from the ROM directory
+	systemRomDirectoryPath := "/home/pi/RetroPie/roms/n64"
+
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(systemRomDirectoryPath, []string{"The New Tetris (USA).n64", "Goldeneye 007 (U) [!].n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+	manager.SetFileContents(bezelDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \"tetris.cfg\""))
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true, patching.WithFallbackTemplate(patching.FallbackTemplate{
+		Name:     "fallback.cfg",
+		Contents: "# {rom_name} ({system})\ninput_overlay = \"{overlay}\"\n",
+		Overlay:  "/opt/retropie/configs/all/retroarch/overlay/{system}.cfg",
+	}))
+	result, _ := patcher.PatchDirectory(bezelDirectoryPath, systemRomDirectoryPath, patching.MatchTypeAlternate)
+	require.NotNil(t, result)
+	assert.Equal(t, 0, result.MissingConfigs)
+
+	// check the contents of the bezel directory to ensure the expected config files exist
+	actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "Goldeneye 007 (U) [!].cfg", "The New Tetris (USA).cfg"}, actualContents)
+
+	// ROMs with a matching config are never generated from the template
+	contents, err := manager.ReadFile(bezelDirectoryPath, "The New Tetris (USA).cfg")
+	require.NoError(t, err)
+	assert.Equal(t, "input_overlay = \"tetris.cfg\"", string(contents))
+
+	contents, err = manager.ReadFile(bezelDirectoryPath, "Goldeneye 007 (U) [!].cfg")
+	require.NoError(t, err)
+	assert.Equal(t, "# Goldeneye 007 (U) [!] (n64)\ninput_overlay = \"/opt/retropie/configs/all/retroarch/overlay/n64.cfg\"\n", string(contents))
+}
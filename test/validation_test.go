@@ -0,0 +1,79 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wamphlett/bezel-project-patcher/pkg/patching"
+)
+
+const overlayDirectoryPath = "/opt/retropie/configs/all/retroarch/overlay/GameBezels/N64"
+
+func TestBrokenConfigsAreNotDuplicated(t *testing.T) {
+	tt := map[string]struct {
+		overlayDirContents       []string
+		expectedBezelDirContents []string
+	}{
+		"valid overlay chain": {
+			overlayDirContents: []string{"New Tetris, The (USA).cfg", "New Tetris, The (USA).png"},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+				"The New Tetris (USA).cfg",
+			},
+		},
+		"missing overlay config": {
+			overlayDirContents: []string{"New Tetris, The (USA).png"},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+			},
+		},
+		"missing overlay image": {
+			overlayDirContents: []string{"New Tetris, The (USA).cfg"},
+			expectedBezelDirContents: []string{
+				"New Tetris, The (USA).cfg",
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// mock the contents of the directories
+			manager := NewStubFileManager()
+			manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+			manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+			manager.SetDirectoryContents(overlayDirectoryPath, tc.overlayDirContents)
+			manager.SetFileContents(bezelDirectoryPath, "New Tetris, The (USA).cfg", []byte("input_overlay = \""+overlayDirectoryPath+"/New Tetris, The (USA).cfg\"\n"))
+			manager.SetFileContents(overlayDirectoryPath, "New Tetris, The (USA).cfg", []byte("overlays = 1\noverlay0_overlay = \"New Tetris, The (USA).png\"\n"))
+
+			// run the patcher
+			patcher := patching.NewPatcher(manager, true, patching.WithOverlayValidation())
+			patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+			// check the contents of the bezel directory to ensure the expected config files exist
+			actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectedBezelDirContents, actualContents)
+		})
+	}
+}
+
+func TestConfigsWithoutOverlayAreValid(t *testing.T) {
+	// mock the contents of the directories
+	manager := NewStubFileManager()
+	manager.SetDirectoryContents(romDirectoryPath, []string{"The New Tetris (USA).n64"})
+	manager.SetDirectoryContents(bezelDirectoryPath, []string{"New Tetris, The (USA).cfg"})
+	manager.SetFileContents(bezelDirectoryPath, "New Tetris, The (USA).cfg", []byte("aspect_ratio_index = 22\n"))
+
+	// run the patcher
+	patcher := patching.NewPatcher(manager, true, patching.WithOverlayValidation())
+	patcher.PatchDirectory(bezelDirectoryPath, romDirectoryPath, patching.MatchTypeFuzzy)
+
+	// check the contents of the bezel directory to ensure the expected config files exist
+	actualContents, err := manager.GetDirectoryContents(bezelDirectoryPath)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"New Tetris, The (USA).cfg", "The New Tetris (USA).cfg"}, actualContents)
+}